@@ -1,18 +1,65 @@
 package serve
 
+import (
+	"time"
+
+	"github.com/jpillora/sizestr"
+)
+
 //Config is a handler configuration
 type Config struct {
-	Directory  string `type:"arg" help:"[directory] from which files will be served"`
-	Auth       string `help:"Enable HTTP basic auth with the chosen username and password (must be in the form 'user:pass')"`
-	LiveReload bool   `help:"Enable LiveReload, a websocket server which triggers browser refresh after each file change"`
-	PushState  bool   `help:"Enable PushState mode, causes missing directory paths to return the root index.html file, instead of a 404. Allows for sane usage of the HTML5 History API." short:"s"`
-	NoIndex    bool   `help:"Disable automatic loading of index.html"`
-	NoSlash    bool   `help:"Disable automatic slash insertion when loading an index.html or directory"`
-	NoList     bool   `help:"Disable directory listing"`
-	NoArchive  bool   `help:"Disable directory archiving (download directories by appending .zip .tar .tar.gz - archives are streamed without buffering)"`
-	NoCache    bool   `help:"Disable cache (file modified time is always now)"`
-	Quiet      bool   `help:"Disable all output"`
-	TimeFmt    string `help:"Set timestamp output format"`
-	Fallback   string `help:"Requests that yeild a 404, will instead proxy through to the provided path (swaps in the appropriate Host header)"`
-	Realm      string `help:"Set the realm for the authentication response"`
+	Directory        string        `type:"arg" help:"[directory] from which files will be served"`
+	Auth             string        `help:"Enable HTTP basic auth with the chosen username and password (must be in the form 'user:pass')"`
+	LiveReload       bool          `help:"Enable LiveReload, which triggers browser refresh after each file change (a client script is injected into HTML pages, and receives events from this server)"`
+	LiveReloadSwap   []string      `help:"Swap changed files of this extension in place instead of reloading the page, in the form '.ext=css' (stylesheets), '.ext=img' (images) or '.ext=reload', may be repeated (.css and common image types are swapped by default)"`
+	LiveReloadIgnore []string      `help:"Ignore changes to files and directories matching this glob (matched against the name and the path from the root), may be repeated (.git, .hg, .svn, node_modules and serve's own hidden directories are always ignored)"`
+	LiveReloadDelay  time.Duration `help:"Wait for changes to settle for this duration before reloading, bursts of changes (such as an editor saving) cause a single reload (defaults to 100ms)"`
+	OnChange         []string      `help:"Run this command when watched files change, before reloading, in the form 'cmd' or 'glob=cmd' (e.g. '*.scss=make css', the glob is matched against the name and the path from the root), may be repeated. Pages are reloaded once the commands succeed, failures are shown in the browser (requires --live-reload)"`
+	Watcher          string        `help:"Filesystem watcher used by LiveReload and the listing caches: notify (filesystem events), poll (for network filesystems, Docker volumes and VM shares, where events are not delivered) or auto (poll when the directory is on such a filesystem) (defaults to auto)"`
+	PollInterval     time.Duration `help:"Interval between scans of the polling watcher (defaults to 1s)"`
+	LiveReloadPort   int           `help:"Also run a LiveReload protocol server on this port, for LiveReload browser extensions (which use 35729)"`
+	PushState        bool          `help:"Enable PushState mode, causes missing directory paths to return the root index.html file, instead of a 404. Allows for sane usage of the HTML5 History API." short:"s"`
+	NoIndex          bool          `help:"Disable automatic loading of index.html"`
+	NoSlash          bool          `help:"Disable automatic slash insertion when loading an index.html or directory"`
+	NoList           bool          `help:"Disable directory listing"`
+	NoArchive        bool          `help:"Disable directory archiving (download directories by appending .zip .tar .tar.gz - archives are streamed without buffering)"`
+	NoChecksum       bool          `help:"Disable checksums (append ?checksum=sha256, sha512 or md5 to a file, or download the virtual SHA256SUMS, SHA512SUMS or MD5SUMS file from any directory)"`
+	DigestHeaders    bool          `help:"Always send RFC 9530 Repr-Digest and Content-Digest headers (sha-256) with files, otherwise they are only sent when requested with Want-Repr-Digest or Want-Content-Digest"`
+	NoCache          bool          `help:"Disable cache (file modified time is always now)"`
+	Quiet            bool          `help:"Disable all output"`
+	TimeFmt          string        `help:"Set timestamp output format"`
+	Fallback         []string      `help:"Requests that yeild a 404, will instead proxy through to the provided path (swaps in the appropriate Host header), may be a unix socket (unix:///path/to.sock), may be repeated to balance requests across several servers"`
+	FallbackOn       []string      `help:"What sends a request to the fallback: missing (files), dir (directories), status:CODE (file responses with this status, e.g. status:403), method:METHOD (e.g. method:POST, or method:!GET for all other methods) or a path glob (e.g. /api/*, matched against the path and its parents), may be repeated (defaults to missing and dir)"`
+	FallbackFirst    bool          `help:"Send every request to the fallback first, serving files only when it responds with a 404 (requests with bodies over 8MB are only sent to the fallback)"`
+	FallbackRecord   string        `help:"Save each request sent to the fallback, along with its response, as a json file in this directory (exchanges with bodies over 32MB are sent, but not saved)"`
+	FallbackReplay   string        `help:"Respond to fallback requests with the recordings in this directory, instead of contacting the fallback (which is used, when set, for requests without a recording)"`
+	FallbackMatch    string        `help:"How replayed requests are matched with recordings: path (method and path), query (also the query) or body (also a hash of the body) (defaults to query)"`
+	FallbackCache    sizestr.Bytes `help:"Cache fallback responses up to this total size (e.g. 64MB), responses are cached when their Cache-Control or Expires headers allow it, and vary by their Vary headers (the Serve-Cache header reports HIT, MISS, STALE or BYPASS)"`
+	FallbackCacheDir string        `help:"Store cached fallback responses in this directory, instead of in memory (requires --fallback-cache)"`
+	FallbackStale    time.Duration `help:"Serve cached fallback responses up to this long after they expire, when the fallback fails (responses may also allow this with stale-if-error)"`
+	FallbackBalance  string        `help:"How requests are spread across multiple fallbacks: round-robin or least-conn (defaults to round-robin)"`
+	FallbackHealth   string        `help:"Path requested from each fallback every health check interval, those which fail to respond with a 2xx or 3xx are skipped until they do (disabled by default)"`
+	FallbackInterval time.Duration `help:"Interval between fallback health checks, fallbacks which fail to connect are also skipped for this duration (defaults to 10s)"`
+	FallbackRetries  int           `help:"Number of other fallbacks tried when an idempotent request (GET, HEAD, OPTIONS, PUT or DELETE without a body) fails to connect"`
+	Proxy            []string      `help:"Proxy requests within a path prefix to another server, instead of serving files, in the form '/prefix=url' (e.g. '/api=http://localhost:8080'), may be repeated and the longest prefix wins. When the url has a path (e.g. 'http://localhost:8080/') it replaces the prefix. The url may be a unix socket (unix:///path/to.sock, or unix:///path/to.sock:/path/). Request headers are set with '+Name:value' or removed with '-Name' after the url (e.g. '/api=http://localhost:8080 +X-Api-Key:dev -Cookie')"`
+	Realm            string        `help:"Set the realm for the authentication response"`
+	ListSort         string        `help:"Default directory listing sort key: name, size, mtime or ext (defaults to name)"`
+	ListOrder        string        `help:"Default directory listing sort order: asc or desc (defaults to asc)"`
+	NaturalSort      bool          `help:"Sort names naturally, comparing runs of digits by value (build-2 before build-10)"`
+	NoDirsFirst      bool          `help:"Disable listing directories before files"`
+	ListCache        bool          `help:"Cache directory listings in memory, each is invalidated by filesystem events (also enables conditional requests for listings)"`
+	DirSizes         bool          `help:"Show recursive directory sizes and file counts in listings, computed in the background and invalidated by filesystem events"`
+	ListStream       bool          `help:"Stream directory listings unsorted as they are read (instead of buffering and sorting), also available with ?stream"`
+	ListWorkers      int           `help:"Number of entries stat'd in parallel when listing a directory (defaults to 16)"`
+	ListMaxDepth     int           `help:"Maximum depth of recursive directory listings, requested with ?depth=N or ?recursive (defaults to 8)"`
+	ListMaxEntries   int           `help:"Maximum number of entries in a recursive directory listing (defaults to 100000)"`
+	Write            bool          `help:"Enable file management (delete, rename/move and create folder) from the directory listing (requires --auth)"`
+	Trash            bool          `help:"Deleted files are moved into a .trash directory in the root, from which they can be restored (requires --write)"`
+	Upload           bool          `help:"Enable uploads from the directory listing, large files use the resumable tus protocol (requires --write)"`
+	UploadDir        string        `help:"Directory within the root which holds partial uploads (defaults to .uploads)"`
+	UploadExpiry     time.Duration `help:"Partial uploads which are not resumed within this duration are removed (defaults to 24h)"`
+	UploadQuota      sizestr.Bytes `help:"Reject uploads which would grow the root directory beyond this size (e.g. 10GB), the trash and previous versions are not counted"`
+	UploadDirQuota   sizestr.Bytes `help:"Reject uploads which would grow their destination directory beyond this size"`
+	UploadAllow      []string      `help:"Only allow uploads with this file extension (.zip) or MIME type (image/*), may be repeated"`
+	UploadOverwrite  string        `help:"When an uploaded file already exists: reject, overwrite, rename (adds a numeric suffix) or version (keeps previous versions in a hidden .versions directory, browsable from the listing) (defaults to reject)"`
 }
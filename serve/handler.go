@@ -3,14 +3,16 @@
 package serve
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"mime"
 	"net/http"
-	"net/http/httputil"
-	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -25,21 +27,49 @@ import (
 
 //Handler is custom file server
 type Handler struct {
-	c            Config
-	root         string
-	hasIndex     bool
-	servedMut    sync.Mutex
-	served       map[string]bool
-	fallback     *httputil.ReverseProxy
-	fallbackHost string
-	watcherMut   sync.Mutex
-	watcher      *fsnotify.Watcher
-	watching     map[string]bool
-	lr           *lrserver.Server
+	c           Config
+	root        string
+	hasIndex    bool
+	servedMut   sync.Mutex
+	served      map[string]bool
+	balancer    *balancer
+	fallback    http.Handler
+	fallbackOn  *fallbackTriggers
+	proxies     []*proxyRule
+	watcherMut  sync.Mutex
+	watcher     fileWatcher
+	watching    map[string]bool
+	lr          *lrserver.Server
+	lrMut       sync.Mutex
+	lrClients   map[chan string]bool
+	lrSwaps     map[string]string
+	lrIgnore    []string
+	lrTimer     *time.Timer
+	lrPending   map[string]time.Time
+	lrFlushMut  sync.Mutex
+	lrRanUntil  time.Time
+	onChange    []changeRule
+	uploadDir   string
+	uploadRel   string
+	uploadsMut  sync.Mutex
+	uploading   map[string]bool
+	quotaMut    sync.Mutex
+	usages      map[string]*dirUsage
+	listingsMut sync.Mutex
+	listings    map[string]*listCache
+	sizesMut    sync.Mutex
+	sizes       map[string]*dirSize
+	sizeQueue   chan string
+	digestCache digestCache
+	handler     http.Handler
+	ctx         context.Context
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+	closeOnce   sync.Once
 }
 
 //NewServer creates a new Server
-func NewHandler(c Config) (http.Handler, error) {
+func NewHandler(c Config) (*Handler, error) {
 	s := &Handler{
 		c:      c,
 		served: map[string]bool{},
@@ -57,69 +87,360 @@ func NewHandler(c Config) (http.Handler, error) {
 		s.hasIndex = true
 	}
 
-	if c.Fallback != "" {
-		u, err := url.Parse(c.Fallback)
+	if len(c.Fallback) > 0 {
+		ups := []*upstream{}
+		for _, f := range c.Fallback {
+			up, err := parseUpstream(f)
+			if err != nil {
+				return nil, err
+			}
+			ups = append(ups, up)
+		}
+		switch s.c.FallbackBalance {
+		case "":
+			s.c.FallbackBalance = balanceRoundRobin
+		case balanceRoundRobin, balanceLeastConn:
+		default:
+			return nil, fmt.Errorf("Invalid fallback balance policy: %s", c.FallbackBalance)
+		}
+		if s.c.FallbackInterval <= 0 {
+			s.c.FallbackInterval = 10 * time.Second
+		}
+		if s.c.FallbackRetries < 0 {
+			return nil, fmt.Errorf("Invalid fallback retries: %d", c.FallbackRetries)
+		}
+		s.balancer = newBalancer(ups, s.c.FallbackBalance, s.c.FallbackRetries, s.c.FallbackInterval)
+		s.fallback = s.balancer
+	}
+
+	var rr *recorder
+	if c.FallbackRecord != "" {
+		if s.fallback == nil {
+			return nil, fmt.Errorf("--fallback-record requires --fallback")
+		}
+		rr = &recorder{dir: c.FallbackRecord, next: s.fallback, quiet: c.Quiet}
+		s.fallback = rr
+	}
+	if c.FallbackCache > 0 {
+		if s.fallback == nil {
+			return nil, fmt.Errorf("--fallback-cache requires --fallback")
+		}
+		s.fallback, err = newResponseCache(s.fallback, int64(c.FallbackCache), c.FallbackCacheDir, c.FallbackStale)
+		if err != nil {
+			return nil, err
+		}
+	} else if c.FallbackCacheDir != "" {
+		return nil, fmt.Errorf("--fallback-cache-dir requires --fallback-cache")
+	}
+	if c.FallbackReplay != "" {
+		switch s.c.FallbackMatch {
+		case "":
+			s.c.FallbackMatch = matchQuery
+		case matchPath, matchQuery, matchBody:
+		default:
+			return nil, fmt.Errorf("Invalid fallback match: %s", c.FallbackMatch)
+		}
+		rp, err := newReplayer(c.FallbackReplay, s.c.FallbackMatch, s.fallback)
 		if err != nil {
 			return nil, err
 		}
-		if !strings.HasPrefix(u.Scheme, "http") {
-			return nil, fmt.Errorf("Invalid fallback protocol scheme")
+		//new recordings are replayed (the recorder may be behind the cache)
+		if rr != nil && filepath.Clean(rr.dir) == filepath.Clean(c.FallbackReplay) {
+			rr.onSave = rp.add
+		}
+		s.fallback = rp
+	}
+	if s.fallback != nil {
+		s.fallbackOn, err = parseFallbackTriggers(c.FallbackOn)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.proxies, err = parseProxyRules(c.Proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.c.ListSort == "" {
+		s.c.ListSort = "name"
+	} else if !listSortKeys[s.c.ListSort] {
+		return nil, fmt.Errorf("Invalid list sort key: %s", c.ListSort)
+	}
+	if s.c.ListOrder == "" {
+		s.c.ListOrder = "asc"
+	} else if s.c.ListOrder != "asc" && s.c.ListOrder != "desc" {
+		return nil, fmt.Errorf("Invalid list order: %s", c.ListOrder)
+	}
+	if s.c.ListWorkers <= 0 {
+		s.c.ListWorkers = 16
+	}
+	if s.c.ListMaxDepth <= 0 {
+		s.c.ListMaxDepth = 8
+	}
+	if s.c.ListMaxEntries <= 0 {
+		s.c.ListMaxEntries = 100000
+	}
+	switch s.c.Watcher {
+	case "":
+		s.c.Watcher = watchAuto
+	case watchAuto, watchNotify, watchPoll:
+	default:
+		return nil, fmt.Errorf("Invalid watcher: %s", c.Watcher)
+	}
+	if s.c.PollInterval <= 0 {
+		s.c.PollInterval = time.Second
+	}
+
+	if c.Write && c.Auth == "" {
+		return nil, fmt.Errorf("--write requires --auth")
+	}
+	if c.Trash && !c.Write {
+		return nil, fmt.Errorf("--trash requires --write")
+	}
+
+	if c.Upload {
+		if !c.Write {
+			return nil, fmt.Errorf("--upload requires --write")
+		}
+		if c.UploadDir == "" {
+			s.c.UploadDir = ".uploads"
+		}
+		if s.c.UploadExpiry <= 0 {
+			s.c.UploadExpiry = 24 * time.Hour
 		}
-		s.fallbackHost = u.Host
-		s.fallback = httputil.NewSingleHostReverseProxy(u)
+		switch s.c.UploadOverwrite {
+		case "":
+			s.c.UploadOverwrite = overwriteReject
+		case overwriteReject, overwriteReplace, overwriteRename, overwriteVersion:
+		default:
+			return nil, fmt.Errorf("Invalid upload overwrite policy: %s", c.UploadOverwrite)
+		}
+		s.uploadRel = s.relpath(s.c.UploadDir)
+		if s.uploadRel == "" || inTrash(s.uploadRel) {
+			return nil, fmt.Errorf("Invalid upload directory: %s", s.c.UploadDir)
+		}
+		s.uploadDir = filepath.Join(c.Directory, filepath.FromSlash(s.uploadRel))
+		s.uploading = map[string]bool{}
+		s.usages = map[string]*dirUsage{}
 	}
 
+	if len(c.OnChange) > 0 && !c.LiveReload {
+		return nil, fmt.Errorf("--on-change requires --live-reload")
+	}
 	if c.LiveReload {
-		s.lr = lrserver.New("serve-lr", lrserver.DefaultPort)
-		discard := log.New(ioutil.Discard, "", 0)
-		s.lr.SetErrorLog(discard)
-		s.lr.SetStatusLog(discard)
+		s.lrClients = map[chan string]bool{}
+		s.lrSwaps, err = lrSwaps(append(lrSwapDefaults, c.LiveReloadSwap...))
+		if err != nil {
+			return nil, err
+		}
+		s.lrIgnore = append(lrIgnoreDefaults, c.LiveReloadIgnore...)
+		if c.Upload {
+			s.lrIgnore = append(s.lrIgnore, s.uploadRel)
+		}
+		for _, glob := range s.lrIgnore {
+			if _, err := path.Match(glob, ""); err != nil {
+				return nil, fmt.Errorf("Invalid live reload ignore pattern: %s", glob)
+			}
+		}
+		if s.c.LiveReloadDelay <= 0 {
+			s.c.LiveReloadDelay = 100 * time.Millisecond
+		}
+		s.lrPending = map[string]time.Time{}
+		s.onChange, err = parseChangeRules(c.OnChange)
+		if err != nil {
+			return nil, err
+		}
+		if c.LiveReloadPort > 0 {
+			s.lr = lrserver.New("serve-lr", uint16(c.LiveReloadPort))
+			discard := log.New(ioutil.Discard, "", 0)
+			s.lr.SetErrorLog(discard)
+			s.lr.SetStatusLog(discard)
+		}
+	}
+
+	if c.ListCache {
+		s.listings = map[string]*listCache{}
+	}
+
+	if c.DirSizes {
+		s.sizes = map[string]*dirSize{}
+		s.sizeQueue = make(chan string, 1024)
+	}
+
+	var auth []string
+	if c.Auth != "" {
+		auth = strings.SplitN(c.Auth, ":", 2)
+		if len(auth) < 2 {
+			return nil, fmt.Errorf("should be in the form 'user:pass'")
+		}
+	}
+
+	if c.LiveReload || c.ListCache || c.DirSizes {
 		s.watching = map[string]bool{}
-		s.watcher, err = fsnotify.NewWatcher()
+		s.watcher, err = s.newWatcher()
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	if s.c.LiveReload {
-		go func() {
-			if err := s.lr.ListenAndServe(); err != nil {
+	//background work, stopped by Close
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	if c.Upload {
+		s.goBackground(func() {
+			t := time.NewTicker(time.Minute)
+			defer t.Stop()
+			for {
+				select {
+				case <-t.C:
+					s.tusExpire()
+				case <-s.ctx.Done():
+					return
+				}
+			}
+		})
+	}
+
+	if c.DirSizes {
+		for i := 0; i < dirSizeWorkers; i++ {
+			queue := s.sizeQueue
+			s.goBackground(func() {
+				s.sizeWorker(queue)
+			})
+		}
+	}
+
+	if s.balancer != nil && c.FallbackHealth != "" {
+		s.goBackground(func() {
+			s.balancer.healthChecks(s.ctx, c.FallbackHealth, s.c.FallbackInterval)
+		})
+	}
+
+	if s.lr != nil {
+		s.goBackground(func() {
+			if err := s.lr.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 				fmt.Printf("LiveReload server closed: %s", err)
 			}
-		}()
-		go func() {
-			for {
-				event := <-s.watcher.Events
-				switch event.Op {
-				case fsnotify.Create, fsnotify.Rename, fsnotify.Write:
-					s.lr.Reload(event.Name)
-				case fsnotify.Remove:
-					if s.watching[event.Name] {
-						delete(s.watching, event.Name)
+		})
+	}
+
+	if s.watcher != nil {
+		s.goBackground(func() {
+			for event := range s.watcher.Events() {
+				s.invalidate(event.Name)
+				if s.c.LiveReload && event.Op&(fsnotify.Create|fsnotify.Rename|fsnotify.Write|fsnotify.Remove) != 0 && !s.ignored(event.Name) {
+					//watch new directories
+					if event.Op&fsnotify.Create != 0 {
+						if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+							name := event.Name
+							s.goBackground(func() {
+								s.watchTree(name)
+							})
+						}
 					}
+					s.changed(event.Name)
+				}
+				if event.Op&fsnotify.Remove != 0 {
+					s.watcherMut.Lock()
+					delete(s.watching, event.Name)
+					s.watcherMut.Unlock()
 				}
 			}
-		}()
+		})
+	}
+
+	//watch the entire tree up front
+	if s.c.LiveReload {
+		s.goBackground(func() {
+			s.watchTree(c.Directory)
+		})
 	}
 
-	h := http.Handler(s)
+	s.handler = http.HandlerFunc(s.serveHTTP)
 	//basic auth
 	if c.Auth != "" {
-		auth := strings.SplitN(c.Auth, ":", 2)
-		if len(auth) < 2 {
-			return nil, fmt.Errorf("should be in the form 'user:pass'")
-		}
-		h = cookieauth.WrapWithRealm(h, auth[0], auth[1], c.Realm)
+		s.handler = cookieauth.WrapWithRealm(s.handler, auth[0], auth[1], c.Realm)
 	}
 	//logging is enabled
 	if !c.Quiet {
-		h = requestlog.WrapWith(h, requestlog.Options{TimeFormat: c.TimeFmt})
+		s.handler = requestlog.WrapWith(s.handler, requestlog.Options{TimeFormat: c.TimeFmt})
 	}
 	//listen
-	return h, nil
+	return s, nil
 }
 
+//ServeHTTP serves files, through the authentication
+//and logging handlers when enabled
 func (s *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+func (s *Handler) serveHTTP(w http.ResponseWriter, r *http.Request) {
+
+	//live reload client and events
+	if s.c.LiveReload {
+		switch r.URL.Path {
+		case lrClientPath:
+			s.lrClient(w, r)
+			return
+		case lrEventsPath:
+			s.lrEvents(w, r)
+			return
+		}
+	}
+	//resumable uploads
+	if s.c.Upload && strings.HasPrefix(r.URL.Path, tusPath) {
+		s.tus(w, r)
+		return
+	}
+	//proxy rules take precedence over files
+	if len(s.proxies) > 0 && s.proxied(w, r) {
+		return
+	}
+	//fallback first, then files when it has none
+	if s.fallback != nil && s.c.FallbackFirst {
+		//the body is kept for the files, larger
+		//bodies are only sent to the fallback
+		body, retry, err := bufferBody(r, fallbackMaxRetry)
+		if err != nil {
+			w.WriteHeader(400)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		if !retry {
+			s.fallback.ServeHTTP(w, r)
+			return
+		}
+		iw := newInterceptWriter(w, map[int]bool{http.StatusNotFound: true})
+		s.fallback.ServeHTTP(iw, r)
+		if iw.intercepted {
+			if body != nil {
+				r.Body = io.NopCloser(bytes.NewReader(body))
+			}
+			s.serveStatic(w, r, false)
+		}
+		return
+	}
+	//files, replaced by the fallback when they respond with a trigger status
+	if s.fallback != nil && len(s.fallbackOn.statuses) > 0 {
+		iw := newInterceptWriter(w, s.fallbackOn.statuses)
+		s.serveStatic(iw, r, true)
+		if iw.intercepted {
+			s.fallback.ServeHTTP(w, r)
+		}
+		return
+	}
+	s.serveStatic(w, r, true)
+}
+
+//serveStatic serves files, directories and file management, deferring
+//to the fallback (when allowed) if one of its triggers match
+func (s *Handler) serveStatic(w http.ResponseWriter, r *http.Request, fallback bool) {
+	//file management
+	if s.c.Write && s.fileop(w, r, fallback) {
+		return
+	}
 
 	path := r.URL.Path
 	//shorthand
@@ -129,6 +450,11 @@ func (s *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			w.Write([]byte(msg))
 		}
 	}
+	//partial uploads are private
+	if s.hidden(s.relpath(path)) {
+		reply(404, "Not found")
+		return
+	}
 	//requested file
 	p := filepath.Join(s.c.Directory, path)
 	//check file or dir
@@ -150,6 +476,15 @@ func (s *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// 	}
 	// }
 
+	//virtual checksum files, which list the directory
+	if alg, ok := sumsFiles[filepath.Base(p)]; ok && missing && !s.c.NoChecksum && !s.c.NoList {
+		dir := filepath.Dir(p)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			s.sums(w, dir, alg)
+			return
+		}
+	}
+
 	if s.c.PushState && missing && filepath.Ext(p) == "" {
 		//missing and pushstate and no ext
 		p = s.root //change to request for the root
@@ -157,10 +492,9 @@ func (s *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		missing = false
 	}
 
-	if s.fallback != nil && (missing || isdir) {
+	if fallback && s.fallback != nil && s.fallbackOn.match(r, missing, isdir) {
 		//fallback proxy enabled
-		r.Host = s.fallbackHost
-		s.fallback.ServeHTTP(w, r)
+		s.serveFallback(w, r)
 		return
 	}
 
@@ -218,6 +552,13 @@ func (s *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	//html responses load the livereload client
+	if s.c.LiveReload {
+		iw := s.injector(w)
+		defer iw.close()
+		w = iw
+	}
+
 	//directory list
 	if isdir {
 		if s.c.NoList {
@@ -235,6 +576,16 @@ func (s *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	//file digest
+	if alg := r.URL.Query().Get("checksum"); alg != "" && !s.c.NoChecksum {
+		if hashes[alg] == nil {
+			reply(400, "Unknown checksum algorithm")
+			return
+		}
+		s.checksum(w, p, info, alg)
+		return
+	}
+
 	//stream file
 	f, err := os.Open(p)
 	if err != nil {
@@ -245,13 +596,7 @@ func (s *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	//add all served file's parent dirs to the watcher
 	if s.c.LiveReload {
 		dir, _ := filepath.Split(p)
-		s.watcherMut.Lock()
-		if _, watching := s.watching[dir]; !watching {
-			if err := s.watcher.Add(dir); err == nil {
-				s.watching[dir] = true
-			}
-		}
-		s.watcherMut.Unlock()
+		s.watch(dir)
 	}
 
 	modtime := info.ModTime()
@@ -263,5 +608,5 @@ func (s *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	s.servedMut.Unlock()
 	//http.ServeContent handles caching and range requests
-	http.ServeContent(w, r, info.Name(), modtime, f)
+	http.ServeContent(s.withDigests(w, r, p, info), r, info.Name(), modtime, f)
 }
@@ -0,0 +1,193 @@
+package serve
+
+import (
+	"context"
+	"net/http"
+	"net/http/httputil"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//balancing policies
+const (
+	balanceRoundRobin = "round-robin"
+	balanceLeastConn  = "least-conn"
+)
+
+//backend is one of the fallback upstreams
+type backend struct {
+	up        *upstream
+	proxy     *httputil.ReverseProxy
+	active    int64
+	mut       sync.Mutex
+	unhealthy bool
+	ejected   time.Time
+}
+
+//available reports whether b passed its last health
+//check, and has not been ejected since
+func (b *backend) available(eject time.Duration) bool {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return !b.unhealthy && time.Since(b.ejected) >= eject
+}
+
+//balancer spreads requests across the fallback upstreams,
+//retrying idempotent requests on other upstreams when
+//they cannot be proxied (such as a refused connection)
+type balancer struct {
+	policy   string
+	retries  int
+	eject    time.Duration
+	backends []*backend
+	next     uint32
+}
+
+//attemptKey holds the *attempt of a request in its context
+type attemptKey struct{}
+
+//attempt records a proxy error, instead of replying, when
+//the request can be retried
+type attempt struct {
+	retry bool
+	err   error
+}
+
+func newBalancer(ups []*upstream, policy string, retries int, eject time.Duration) *balancer {
+	b := &balancer{policy: policy, retries: retries, eject: eject}
+	for _, up := range ups {
+		be := &backend{up: up, proxy: newProxy(up)}
+		be.proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+			if r.Context().Err() == nil {
+				//passive ejection
+				be.mut.Lock()
+				be.ejected = time.Now()
+				be.mut.Unlock()
+			}
+			if a, ok := r.Context().Value(attemptKey{}).(*attempt); ok && a.retry {
+				a.err = err
+				return
+			}
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("Fallback failed: " + err.Error()))
+		}
+		b.backends = append(b.backends, be)
+	}
+	return b
+}
+
+func (b *balancer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	retries := 0
+	if idempotent(r) {
+		retries = b.retries
+	}
+	tried := map[*backend]bool{}
+	a := &attempt{}
+	r = r.WithContext(context.WithValue(r.Context(), attemptKey{}, a))
+	for {
+		be := b.pick(tried)
+		tried[be] = true
+		a.retry = retries > 0 && len(tried) < len(b.backends)
+		a.err = nil
+		atomic.AddInt64(&be.active, 1)
+		be.proxy.ServeHTTP(w, r)
+		atomic.AddInt64(&be.active, -1)
+		if a.err == nil {
+			return
+		}
+		retries--
+	}
+}
+
+//pick chooses a backend which has not been tried, preferring
+//those which are available
+func (b *balancer) pick(tried map[*backend]bool) *backend {
+	n := len(b.backends)
+	start := int(atomic.AddUint32(&b.next, 1)-1) % n
+	var best, fallback *backend
+	for i := 0; i < n; i++ {
+		be := b.backends[(start+i)%n]
+		if tried[be] {
+			continue
+		}
+		if fallback == nil {
+			fallback = be
+		}
+		if !be.available(b.eject) {
+			continue
+		}
+		if b.policy == balanceRoundRobin {
+			return be
+		}
+		if best == nil || atomic.LoadInt64(&be.active) < atomic.LoadInt64(&best.active) {
+			best = be
+		}
+	}
+	if best != nil {
+		return best
+	}
+	//none are available, try one anyway
+	return fallback
+}
+
+//healthChecks requests path from each upstream every interval,
+//those which fail to respond with a 2xx or 3xx are unavailable
+//until they do
+func (b *balancer) healthChecks(ctx context.Context, path string, interval time.Duration) {
+	timeout := interval
+	if timeout > 5*time.Second {
+		timeout = 5 * time.Second
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		var wg sync.WaitGroup
+		for _, be := range b.backends {
+			wg.Add(1)
+			go func(be *backend) {
+				defer wg.Done()
+				ok := be.check(ctx, path, timeout)
+				be.mut.Lock()
+				be.unhealthy = !ok
+				if ok {
+					be.ejected = time.Time{}
+				}
+				be.mut.Unlock()
+			}(be)
+		}
+		wg.Wait()
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+//check requests path from b's upstream
+func (b *backend) check(ctx context.Context, path string, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	u := *b.up.url
+	u.Path, u.RawPath, u.RawQuery = path, "", ""
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return false
+	}
+	resp, err := b.up.transport().RoundTrip(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode < 400
+}
+
+//idempotent reports whether r may be safely sent again
+func idempotent(r *http.Request) bool {
+	switch r.Method {
+	case "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE":
+		return r.ContentLength == 0 && len(r.TransferEncoding) == 0 && r.Header.Get("Upgrade") == ""
+	}
+	return false
+}
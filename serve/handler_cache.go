@@ -0,0 +1,395 @@
+package serve
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//cacheHeader reports how the cache handled a response:
+//HIT, MISS, STALE or BYPASS
+const cacheHeader = "Serve-Cache"
+
+//cacheStatuses may be cached (RFC 9111, heuristically cacheable)
+var cacheStatuses = map[int]bool{
+	200: true, 203: true, 204: true, 300: true, 301: true,
+	308: true, 404: true, 405: true, 410: true, 414: true, 501: true,
+}
+
+//cacheEntry is a cached response, the body is held in
+//memory, or beside the entry's json file on disk
+type cacheEntry struct {
+	Key     string        `json:"key"`
+	Primary string        `json:"primary"`
+	Vary    []string      `json:"vary,omitempty"`
+	Status  int           `json:"status"`
+	Header  http.Header   `json:"header"`
+	Stored  time.Time     `json:"stored"`
+	Expires time.Time     `json:"expires"`
+	Stale   time.Duration `json:"stale"`
+	Size    int64         `json:"size"`
+	body    []byte
+}
+
+//responseCache is a shared HTTP cache in front of the fallback,
+//responses are stored when their Cache-Control or Expires headers
+//allow it (no heuristic freshness), varying by their Vary headers,
+//least recently used responses are evicted beyond the size limit
+type responseCache struct {
+	next    http.Handler
+	max     int64
+	dir     string
+	stale   time.Duration
+	mut     sync.Mutex
+	size    int64
+	lru     *list.List
+	entries map[string]*list.Element
+	vary    map[string][]string
+}
+
+func newResponseCache(next http.Handler, max int64, dir string, stale time.Duration) (*responseCache, error) {
+	c := &responseCache{
+		next:    next,
+		max:     max,
+		dir:     dir,
+		stale:   stale,
+		lru:     list.New(),
+		entries: map[string]*list.Element{},
+		vary:    map[string][]string{},
+	}
+	if dir == "" {
+		return c, nil
+	}
+	//load the entries stored on disk, oldest first
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	loaded := []*cacheEntry{}
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		e := &cacheEntry{}
+		if json.Unmarshal(b, e) == nil && e.Key != "" {
+			loaded = append(loaded, e)
+		}
+	}
+	sort.Slice(loaded, func(i, j int) bool {
+		return loaded[i].Stored.Before(loaded[j].Stored)
+	})
+	for _, e := range loaded {
+		c.add(e)
+	}
+	return c, nil
+}
+
+func (c *responseCache) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reqCC := cacheControl(r.Header.Get("Cache-Control"))
+	_, noStore := reqCC["no-store"]
+	if (r.Method != "GET" && r.Method != "HEAD") || noStore ||
+		r.Header.Get("Authorization") != "" || r.Header.Get("Upgrade") != "" {
+		w.Header().Set(cacheHeader, "BYPASS")
+		c.next.ServeHTTP(w, r)
+		return
+	}
+	primary := r.URL.RequestURI()
+	e := c.lookup(primary, r)
+	_, noCache := reqCC["no-cache"]
+	if reqCC["max-age"] == "0" {
+		noCache = true
+	}
+	now := time.Now()
+	if e != nil && !noCache && now.Before(e.Expires) {
+		if c.serve(w, r, e, "HIT", nil) {
+			return
+		}
+	}
+	//stale entries are served when the fallback fails
+	if e != nil && now.After(e.Expires.Add(e.Stale)) {
+		e = nil
+	}
+	w.Header().Set(cacheHeader, "MISS")
+	cw := &cacheWriter{ResponseWriter: w, c: c, r: r, stale: e, outer: w.Header().Clone()}
+	c.next.ServeHTTP(cw, r)
+	if cw.store && r.Method == "GET" {
+		c.save(primary, r, cw)
+	}
+}
+
+//lookup finds the entry of primary which matches r's Vary headers
+func (c *responseCache) lookup(primary string, r *http.Request) *cacheEntry {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	el, ok := c.entries[cacheKey(primary, c.vary[primary], r.Header)]
+	if !ok {
+		return nil
+	}
+	c.lru.MoveToFront(el)
+	return el.Value.(*cacheEntry)
+}
+
+//serve writes e as the response, reporting whether it could be (its
+//body may have been removed), when outer is set, the headers of w
+//are reset to it, dropping those of a failed fallback response
+func (c *responseCache) serve(w http.ResponseWriter, r *http.Request, e *cacheEntry, status string, outer http.Header) bool {
+	body := e.body
+	if c.dir != "" {
+		b, err := os.ReadFile(filepath.Join(c.dir, cacheFile(e.Key)+".body"))
+		if err != nil {
+			return false
+		}
+		body = b
+	}
+	h := w.Header()
+	if outer != nil {
+		for k := range h {
+			delete(h, k)
+		}
+		for k, v := range outer {
+			h[k] = v
+		}
+	}
+	for k, v := range e.Header {
+		h[k] = v
+	}
+	h.Set("Age", strconv.Itoa(int(time.Since(e.Stored).Seconds())))
+	h.Set("Content-Length", strconv.Itoa(len(body)))
+	h.Set(cacheHeader, status)
+	w.WriteHeader(e.Status)
+	if r.Method != "HEAD" {
+		w.Write(body)
+	}
+	return true
+}
+
+//save stores the response captured by cw
+func (c *responseCache) save(primary string, r *http.Request, cw *cacheWriter) {
+	h := cw.Header().Clone()
+	h.Del(cacheHeader)
+	//headers of outer layers aren't part of the response
+	for k, v := range cw.outer {
+		if strings.Join(h[k], "\n") == strings.Join(v, "\n") {
+			delete(h, k)
+		}
+	}
+	e := &cacheEntry{
+		Primary: primary,
+		Vary:    cw.vary,
+		Key:     cacheKey(primary, cw.vary, r.Header),
+		Status:  cw.status,
+		Header:  h,
+		Stored:  cw.date,
+		Expires: cw.expires,
+		Stale:   cw.staleIfError,
+		Size:    int64(len(cw.body)),
+		body:    cw.body,
+	}
+	if c.dir != "" {
+		name := filepath.Join(c.dir, cacheFile(e.Key))
+		meta, _ := json.Marshal(e)
+		if err := os.WriteFile(name+".body", e.body, 0644); err != nil {
+			return
+		}
+		if err := os.WriteFile(name+".json", meta, 0644); err != nil {
+			return
+		}
+		e.body = nil
+	}
+	c.mut.Lock()
+	c.add(e)
+	c.mut.Unlock()
+}
+
+//add indexes e, evicting the least recently used entries
+//beyond the size limit (c.mut must be held)
+func (c *responseCache) add(e *cacheEntry) {
+	if el, ok := c.entries[e.Key]; ok {
+		c.size -= el.Value.(*cacheEntry).Size
+		c.lru.Remove(el)
+	}
+	c.entries[e.Key] = c.lru.PushFront(e)
+	c.vary[e.Primary] = e.Vary
+	c.size += e.Size
+	for c.size > c.max {
+		el := c.lru.Back()
+		old := el.Value.(*cacheEntry)
+		c.lru.Remove(el)
+		delete(c.entries, old.Key)
+		c.size -= old.Size
+		if c.dir != "" {
+			name := filepath.Join(c.dir, cacheFile(old.Key))
+			os.Remove(name + ".json")
+			os.Remove(name + ".body")
+		}
+	}
+}
+
+//cacheWriter passes the fallback response through, capturing it
+//when it may be stored, or replacing it with the stale entry
+//when the fallback fails
+type cacheWriter struct {
+	http.ResponseWriter
+	c            *responseCache
+	r            *http.Request
+	stale        *cacheEntry
+	outer        http.Header
+	wroteHeader  bool
+	replaced     bool
+	store        bool
+	status       int
+	vary         []string
+	date         time.Time
+	expires      time.Time
+	staleIfError time.Duration
+	body         []byte
+}
+
+func (cw *cacheWriter) WriteHeader(code int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	if code >= 500 && cw.stale != nil && cw.c.serve(cw.ResponseWriter, cw.r, cw.stale, "STALE", cw.outer) {
+		cw.replaced = true
+		return
+	}
+	cw.status = code
+	cw.store = cw.storable()
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *cacheWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(200)
+	}
+	if cw.replaced {
+		return len(b), nil
+	}
+	if cw.store {
+		if int64(len(cw.body)+len(b)) > cw.c.max {
+			cw.store = false
+			cw.body = nil
+		} else {
+			cw.body = append(cw.body, b...)
+		}
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+func (cw *cacheWriter) Flush() {
+	if cw.replaced {
+		return
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+//storable reports whether the response may be cached, and when it expires
+func (cw *cacheWriter) storable() bool {
+	h := cw.Header()
+	if !cacheStatuses[cw.status] || h.Get("Set-Cookie") != "" {
+		return false
+	}
+	cc := cacheControl(h.Get("Cache-Control"))
+	for _, d := range []string{"no-store", "no-cache", "private"} {
+		if _, ok := cc[d]; ok {
+			return false
+		}
+	}
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "*" {
+				return false
+			}
+			if name != "" {
+				cw.vary = append(cw.vary, name)
+			}
+		}
+	}
+	sort.Strings(cw.vary)
+	cw.date = time.Now()
+	if d, err := http.ParseTime(h.Get("Date")); err == nil && d.Before(cw.date) {
+		cw.date = d
+	}
+	//freshness lifetime, less the age upstream
+	var lifetime time.Duration
+	if v, ok := cc["s-maxage"]; ok {
+		lifetime = seconds(v)
+	} else if v, ok := cc["max-age"]; ok {
+		lifetime = seconds(v)
+	} else if exp, err := http.ParseTime(h.Get("Expires")); err == nil {
+		lifetime = exp.Sub(cw.date)
+	}
+	if age := h.Get("Age"); age != "" {
+		lifetime -= seconds(age)
+	}
+	cw.staleIfError = cw.c.stale
+	if v, ok := cc["stale-if-error"]; ok && seconds(v) > cw.staleIfError {
+		cw.staleIfError = seconds(v)
+	}
+	//responses which are already stale are kept for when the fallback fails
+	if lifetime <= 0 && cw.staleIfError <= 0 {
+		return false
+	}
+	cw.expires = cw.date.Add(lifetime)
+	return true
+}
+
+//cacheControl parses the directives of a Cache-Control header
+func cacheControl(header string) map[string]string {
+	directives := map[string]string{}
+	for _, d := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(d), "=", 2)
+		if kv[0] == "" {
+			continue
+		}
+		v := ""
+		if len(kv) == 2 {
+			v = strings.Trim(kv[1], `"`)
+		}
+		directives[strings.ToLower(kv[0])] = v
+	}
+	return directives
+}
+
+//seconds parses a delta-seconds value
+func seconds(v string) time.Duration {
+	n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return time.Duration(n) * time.Second
+}
+
+//cacheKey identifies a response by its primary key
+//and the request headers it varies by
+func cacheKey(primary string, vary []string, h http.Header) string {
+	key := primary
+	for _, name := range vary {
+		key += fmt.Sprintf("\n%s: %s", name, strings.Join(h.Values(name), ", "))
+	}
+	return key
+}
+
+//cacheFile names the files of a cache entry on disk
+func cacheFile(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
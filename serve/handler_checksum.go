@@ -0,0 +1,124 @@
+package serve
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+//checksum algorithms
+var hashes = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+//sumsFiles are generated in every directory
+var sumsFiles = map[string]string{
+	"MD5SUMS":    "md5",
+	"SHA256SUMS": "sha256",
+	"SHA512SUMS": "sha512",
+}
+
+//digestCacheSize is the number of digests held
+//before the cache is emptied
+const digestCacheSize = 8192
+
+type digestKey struct {
+	alg, path string
+	size      int64
+	mtime     int64
+}
+
+//digestCache holds file digests, keyed by
+//path, size and modified time
+type digestCache struct {
+	mut     sync.Mutex
+	digests map[digestKey][]byte
+}
+
+//digest returns the alg digest of the file at p
+func (c *digestCache) digest(alg, p string, info os.FileInfo) ([]byte, error) {
+	k := digestKey{alg, p, info.Size(), info.ModTime().UnixNano()}
+	c.mut.Lock()
+	sum, ok := c.digests[k]
+	c.mut.Unlock()
+	if ok {
+		return sum, nil
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := hashes[alg]()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	sum = h.Sum(nil)
+	c.mut.Lock()
+	if c.digests == nil || len(c.digests) >= digestCacheSize {
+		c.digests = map[digestKey][]byte{}
+	}
+	c.digests[k] = sum
+	c.mut.Unlock()
+	return sum, nil
+}
+
+//checksum replies with the hex digest of the file at p
+func (s *Handler) checksum(w http.ResponseWriter, p string, info os.FileInfo, alg string) {
+	sum, err := s.digestCache.digest(alg, p, info)
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(200)
+	w.Write([]byte(hex.EncodeToString(sum) + "\n"))
+}
+
+//sums replies with a checksum file (in the format of sha256sum
+//and friends) covering each of the files within dir
+func (s *Handler) sums(w http.ResponseWriter, dir, alg string) {
+	path, _ := filepath.Rel(s.c.Directory, dir)
+	files, err := s.readdir(dir, path, func(string) bool { return true })
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+	flush := func() {}
+	if f, ok := w.(http.Flusher); ok {
+		flush = f.Flush
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(200)
+	for _, f := range files {
+		if !f.Accessible || f.IsDir {
+			continue
+		}
+		p := filepath.Join(dir, f.Name)
+		info, err := os.Stat(p)
+		if err != nil {
+			continue
+		}
+		sum, err := s.digestCache.digest(alg, p, info)
+		if err != nil {
+			continue
+		}
+		io.WriteString(w, hex.EncodeToString(sum)+"  "+f.Name+"\n")
+		flush()
+	}
+}
@@ -0,0 +1,29 @@
+package serve
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSumsFiles(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644)
+	for _, nolist := range []bool{false, true} {
+		h, err := NewHandler(Config{Directory: dir, NoList: nolist, Quiet: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/SHA256SUMS", nil))
+		h.Close()
+		listed := strings.Contains(w.Body.String(), "a.txt")
+		if nolist && (w.Code != 404 || listed) {
+			t.Errorf("no list: got %d %q", w.Code, w.Body)
+		} else if !nolist && (w.Code != 200 || !listed) {
+			t.Errorf("got %d %q", w.Code, w.Body)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package serve
+
+import "context"
+
+//goBackground runs fn in a goroutine which Close waits for
+func (s *Handler) goBackground(fn func()) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		fn()
+	}()
+}
+
+//stop signals all background work to exit
+func (s *Handler) stop() {
+	s.closeOnce.Do(func() {
+		s.cancel()
+		if s.watcher != nil {
+			s.watcher.Close()
+		}
+		if s.c.LiveReload {
+			s.lrMut.Lock()
+			if s.lrTimer != nil {
+				s.lrTimer.Stop()
+			}
+			s.lrMut.Unlock()
+		}
+		if s.c.DirSizes {
+			s.sizesMut.Lock()
+			close(s.sizeQueue)
+			s.sizeQueue = nil
+			s.sizesMut.Unlock()
+		}
+	})
+}
+
+//Close stops the watcher, the LiveReload server, and all background
+//goroutines, waiting for them to exit. Live reload event streams are
+//ended, though other requests in flight are unaffected
+func (s *Handler) Close() error {
+	s.stop()
+	var err error
+	if s.lr != nil {
+		err = s.lr.Close()
+	}
+	s.wait()
+	return err
+}
+
+//Shutdown is Close, though the LiveReload server is shutdown
+//gracefully, and waiting is abandoned once ctx is done
+func (s *Handler) Shutdown(ctx context.Context) error {
+	s.stop()
+	if s.lr != nil {
+		if err := s.lr.Shutdown(ctx); err != nil {
+			return err
+		}
+	}
+	done := make(chan struct{})
+	go func() {
+		s.wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+//wait waits for background goroutines, and for
+//any on change commands to be cancelled
+func (s *Handler) wait() {
+	s.wg.Wait()
+	s.lrFlushMut.Lock()
+	s.lrFlushMut.Unlock()
+}
@@ -0,0 +1,38 @@
+package serve
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestHandlerClose(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for _, watcher := range []string{watchNotify, watchPoll} {
+		h, err := NewHandler(Config{
+			Directory:  t.TempDir(),
+			Auth:       "u:p",
+			Write:      true,
+			Upload:     true,
+			LiveReload: true,
+			OnChange:   []string{"true"},
+			ListCache:  true,
+			DirSizes:   true,
+			Watcher:    watcher,
+			Quiet:      true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := h.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("leaked %d goroutines", runtime.NumGoroutine()-before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
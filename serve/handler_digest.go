@@ -0,0 +1,136 @@
+package serve
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+//RFC 9530 digest fields (https://www.rfc-editor.org/rfc/rfc9530)
+
+//digestAlgs maps RFC 9530 algorithm keys to hashes
+var digestAlgs = map[string]string{
+	"sha-256": "sha256",
+	"sha-512": "sha512",
+}
+
+//wantDigest chooses the most preferred supported algorithm
+//from a Want-*-Digest field, or "" when there is none
+func wantDigest(field string) string {
+	best, weight := "", 0
+	for _, member := range strings.Split(field, ",") {
+		kv := strings.SplitN(strings.TrimSpace(member), "=", 2)
+		key := strings.ToLower(kv[0])
+		w := 1
+		if len(kv) == 2 {
+			n, err := strconv.Atoi(strings.TrimSpace(kv[1]))
+			if err != nil {
+				continue
+			}
+			w = n
+		}
+		if _, ok := digestAlgs[key]; ok && w > weight {
+			best, weight = key, w
+		}
+	}
+	return best
+}
+
+//digestField formats a digest as a structured field dictionary
+func digestField(key string, sum []byte) string {
+	return key + "=:" + base64.StdEncoding.EncodeToString(sum) + ":"
+}
+
+//digestWriter adds digest fields to a file response once its
+//status (and range) is known, Repr-Digest covers the entire
+//file, Content-Digest covers the bytes actually sent
+type digestWriter struct {
+	http.ResponseWriter
+	s             *Handler
+	r             *http.Request
+	p             string
+	info          os.FileInfo
+	repr, content string
+	wroteHeader   bool
+}
+
+//withDigests wraps w when the request (or configuration) wants digests
+func (s *Handler) withDigests(w http.ResponseWriter, r *http.Request, p string, info os.FileInfo) http.ResponseWriter {
+	d := &digestWriter{
+		ResponseWriter: w,
+		s:              s,
+		r:              r,
+		p:              p,
+		info:           info,
+		repr:           wantDigest(r.Header.Get("Want-Repr-Digest")),
+		content:        wantDigest(r.Header.Get("Want-Content-Digest")),
+	}
+	if s.c.DigestHeaders {
+		if d.repr == "" {
+			d.repr = "sha-256"
+		}
+		if d.content == "" {
+			d.content = "sha-256"
+		}
+	}
+	if d.repr == "" && d.content == "" {
+		return w
+	}
+	return d
+}
+
+func (d *digestWriter) WriteHeader(code int) {
+	if d.wroteHeader {
+		return
+	}
+	d.wroteHeader = true
+	h := d.Header()
+	full := code == 200
+	partial := code == 206 && !strings.HasPrefix(h.Get("Content-Type"), "multipart/")
+	if d.repr != "" && (full || code == 206) {
+		if sum, err := d.s.digestCache.digest(digestAlgs[d.repr], d.p, d.info); err == nil {
+			h.Set("Repr-Digest", digestField(d.repr, sum))
+		}
+	}
+	if d.content != "" && d.r.Method != "HEAD" {
+		if full {
+			if sum, err := d.s.digestCache.digest(digestAlgs[d.content], d.p, d.info); err == nil {
+				h.Set("Content-Digest", digestField(d.content, sum))
+			}
+		} else if partial {
+			if sum, err := d.rangeDigest(h.Get("Content-Range")); err == nil {
+				h.Set("Content-Digest", digestField(d.content, sum))
+			}
+		}
+	}
+	d.ResponseWriter.WriteHeader(code)
+}
+
+func (d *digestWriter) Write(b []byte) (int, error) {
+	if !d.wroteHeader {
+		d.WriteHeader(200)
+	}
+	return d.ResponseWriter.Write(b)
+}
+
+//rangeDigest hashes the bytes described by a Content-Range
+func (d *digestWriter) rangeDigest(contentRange string) ([]byte, error) {
+	var start, end, size int64
+	if _, err := fmt.Sscanf(contentRange, "bytes %d-%d/%d", &start, &end, &size); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(d.p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := hashes[digestAlgs[d.content]]()
+	if _, err := io.Copy(h, io.NewSectionReader(f, start, end-start+1)); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,76 @@
+package serve
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDigestHeaders(t *testing.T) {
+	dir := t.TempDir()
+	body := []byte("hello world")
+	os.WriteFile(filepath.Join(dir, "a.txt"), body, 0644)
+	sum256 := func(b []byte) string {
+		s := sha256.Sum256(b)
+		return digestField("sha-256", s[:])
+	}
+	sum512 := func(b []byte) string {
+		s := sha512.Sum512(b)
+		return digestField("sha-512", s[:])
+	}
+	for _, test := range []struct {
+		config                Config
+		method, rng           string
+		wantRepr, wantContent string
+		status                int
+		repr, content         string
+	}{
+		//only when asked for
+		{Config{}, "GET", "", "", "", 200, "", ""},
+		{Config{}, "GET", "", "sha-256", "sha-256", 200, sum256(body), sum256(body)},
+		{Config{DigestHeaders: true}, "GET", "", "", "", 200, sum256(body), sum256(body)},
+		//the most preferred algorithm wins
+		{Config{}, "GET", "", "sha-256=1, sha-512=5", "md5=9, sha-512", 200, sum512(body), sum512(body)},
+		//ranges digest the whole file and the bytes sent
+		{Config{}, "GET", "bytes=0-4", "sha-256", "sha-256", 206, sum256(body), sum256(body[0:5])},
+		{Config{}, "GET", "bytes=-5", "sha-256", "sha-256", 206, sum256(body), sum256(body[6:])},
+		//multipart content isn't digested
+		{Config{}, "GET", "bytes=0-1,3-4", "sha-256", "sha-256", 206, sum256(body), ""},
+		//unsatisfiable ranges have no representation
+		{Config{}, "GET", "bytes=50-60", "sha-256", "sha-256", 416, "", ""},
+		//nothing is sent
+		{Config{}, "HEAD", "", "sha-256", "sha-256", 200, sum256(body), ""},
+	} {
+		c := test.config
+		c.Directory, c.Quiet = dir, true
+		h, err := NewHandler(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest(test.method, "/a.txt", nil)
+		if test.rng != "" {
+			r.Header.Set("Range", test.rng)
+		}
+		if test.wantRepr != "" {
+			r.Header.Set("Want-Repr-Digest", test.wantRepr)
+		}
+		if test.wantContent != "" {
+			r.Header.Set("Want-Content-Digest", test.wantContent)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		h.Close()
+		if w.Code != test.status {
+			t.Errorf("%s %q: got status %d, want %d", test.method, test.rng, w.Code, test.status)
+		}
+		if got := w.Header().Get("Repr-Digest"); got != test.repr {
+			t.Errorf("%s %q: got Repr-Digest %q, want %q", test.method, test.rng, got, test.repr)
+		}
+		if got := w.Header().Get("Content-Digest"); got != test.content {
+			t.Errorf("%s %q: got Content-Digest %q, want %q", test.method, test.rng, got, test.content)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package serve
+
+import (
+	"path/filepath"
+	"time"
+)
+
+//listCache holds the entries of a directory until the
+//watcher reports a change within it
+type listCache struct {
+	ready   bool
+	files   []listFile
+	modtime time.Time
+}
+
+//cachedir returns the (possibly cached) entries of dir, along
+//with the time at which they were read
+func (s *Handler) cachedir(dir, path string) ([]listFile, time.Time, error) {
+	dir = filepath.Clean(dir)
+	s.listingsMut.Lock()
+	if c, ok := s.listings[dir]; ok && c.ready {
+		s.listingsMut.Unlock()
+		return c.files, c.modtime, nil
+	}
+	//placeholder, removed by any change which
+	//occurs while the directory is being read
+	c := &listCache{}
+	s.listings[dir] = c
+	s.listingsMut.Unlock()
+	s.watch(dir)
+	files, err := s.readdir(dir, path, func(string) bool { return true })
+	s.listingsMut.Lock()
+	defer s.listingsMut.Unlock()
+	if err != nil {
+		if s.listings[dir] == c {
+			delete(s.listings, dir)
+		}
+		return nil, time.Time{}, err
+	}
+	//truncated to match the precision of Last-Modified
+	modtime := time.Now().Truncate(time.Second)
+	if s.listings[dir] == c {
+		c.files = files
+		c.modtime = modtime
+		c.ready = true
+	}
+	return files, modtime, nil
+}
+
+//uncache removes the listings affected by a change to p,
+//its parent directory and p itself when it is a directory
+func (s *Handler) uncache(p string) {
+	p = filepath.Clean(p)
+	s.listingsMut.Lock()
+	delete(s.listings, p)
+	delete(s.listings, filepath.Dir(p))
+	s.listingsMut.Unlock()
+}
+
+//invalidate removes the cached listings and sizes affected by a
+//change to p, without waiting for the watcher to report it
+func (s *Handler) invalidate(p string) {
+	if s.c.ListCache {
+		s.uncache(p)
+	}
+	if s.c.DirSizes {
+		s.unsize(p)
+	}
+}
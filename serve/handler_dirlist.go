@@ -2,15 +2,22 @@ package serve
 
 import (
 	"bytes"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"hash/fnv"
 	"html/template"
+	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jpillora/serve/serve/static"
@@ -41,7 +48,24 @@ type listDir struct {
 	NumFiles, NumDirs int
 	TotalSize         int64
 	Archive           bool
+	Write, Restore    bool
+	Upload            bool
+	Sort, Order       string
+	Filter            string
+	Offset, Limit     int
+	Stream            bool
+	DirSizes, Pending bool
 	Files             []listFile
+	defSort, defOrder string
+	defStream         bool
+	more              bool
+}
+
+//listRow is a file rendered by the html "row" template
+type listRow struct {
+	listFile
+	Write, Restore bool
+	DirSizes       bool
 }
 
 type listFile struct {
@@ -50,23 +74,308 @@ type listFile struct {
 	IsDir      bool
 	Size       int64
 	Mtime      time.Time
+	Versions   string
+	NumFiles   int
+	Pending    bool
+	Digest     string `json:",omitempty" xml:",omitempty"`
+	symlink    bool
 }
 
-type byName []listFile
+//listing sort keys
+var listSortKeys = map[string]bool{"name": true, "size": true, "mtime": true, "ext": true}
+
+//byKey sorts files by the chosen key, directories
+//first unless mixed
+type byKey struct {
+	files   []listFile
+	key     string
+	desc    bool
+	natural bool
+	mixed   bool
+}
 
-func (a byName) Len() int           { return len(a) }
-func (a byName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
-func (a byName) Less(i, j int) bool {
-	// make sorting case insensitive
-	var file1 = strings.ToLower(a[i].Name)
-	var file2 = strings.ToLower(a[j].Name)
+func (a byKey) Len() int      { return len(a.files) }
+func (a byKey) Swap(i, j int) { a.files[i], a.files[j] = a.files[j], a.files[i] }
+func (a byKey) Less(i, j int) bool {
+	f1, f2 := &a.files[i], &a.files[j]
 
 	// list directories first
-	if a[i].IsDir != a[j].IsDir {
-		return a[i].IsDir
+	if !a.mixed && f1.IsDir != f2.IsDir {
+		return f1.IsDir
+	}
+
+	c := 0
+	switch a.key {
+	case "size":
+		c = compareInt(f1.Size, f2.Size)
+	case "mtime":
+		c = compareInt(f1.Mtime.UnixNano(), f2.Mtime.UnixNano())
+	case "ext":
+		c = strings.Compare(strings.ToLower(filepath.Ext(f1.Name)), strings.ToLower(filepath.Ext(f2.Name)))
+	}
+	//ties are broken by name
+	if c == 0 && a.natural {
+		c = naturalCompare(f1.Name, f2.Name)
+	} else if c == 0 {
+		c = compareName(f1.Name, f2.Name)
+	}
+	if a.desc {
+		return c > 0
+	}
+	return c < 0
+}
+
+//byKey sorts using the configured name ordering
+func (s *Handler) byKey(files []listFile, key string, desc bool) byKey {
+	return byKey{
+		files:   files,
+		key:     key,
+		desc:    desc,
+		natural: s.c.NaturalSort,
+		mixed:   s.c.NoDirsFirst,
+	}
+}
+
+func compareInt(a, b int64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+//compareName is case insensitive
+func compareName(a, b string) int {
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+//listFilter matches names containing q, or when q
+//contains wildcards, names matching the glob q
+func listFilter(q string) (func(name string) bool, error) {
+	q = strings.ToLower(q)
+	if q == "" {
+		return func(string) bool { return true }, nil
+	}
+	if !strings.ContainsAny(q, "*?[") {
+		return func(name string) bool {
+			return strings.Contains(strings.ToLower(name), q)
+		}, nil
+	}
+	if _, err := path.Match(q, ""); err != nil {
+		return nil, err
+	}
+	return func(name string) bool {
+		ok, _ := path.Match(q, strings.ToLower(name))
+		return ok
+	}, nil
+}
+
+//Link returns the query string of this listing
+//with the given key value pairs replaced
+func (l *listDir) Link(kv ...string) string {
+	v := url.Values{}
+	set := func(k, val, def string) {
+		if val != "" && val != def {
+			v.Set(k, val)
+		}
+	}
+	set("sort", l.Sort, l.defSort)
+	set("order", l.Order, l.defOrder)
+	set("q", l.Filter, "")
+	set("limit", strconv.Itoa(l.Limit), "0")
+	set("stream", boolParam(l.Stream), boolParam(l.defStream))
+	for i := 0; i+1 < len(kv); i += 2 {
+		v.Del(kv[i])
+		switch kv[i] {
+		case "sort":
+			set(kv[i], kv[i+1], l.defSort)
+		case "order":
+			set(kv[i], kv[i+1], l.defOrder)
+		case "stream":
+			set(kv[i], kv[i+1], boolParam(l.defStream))
+		default:
+			set(kv[i], kv[i+1], "")
+		}
+	}
+	if len(v) == 0 {
+		return "?"
+	}
+	return "?" + v.Encode()
+}
+
+//boolParam formats a boolean query parameter
+func boolParam(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+//SortLink sorts by key, toggling the order when
+//already sorted by key, sorted listings aren't streamed
+func (l *listDir) SortLink(key string) string {
+	order := "asc"
+	if l.Sort == key && l.Order == "asc" {
+		order = "desc"
+	}
+	return l.Link("sort", key, "order", order, "stream", "0")
+}
+
+//Arrow indicates the current sort key and order
+func (l *listDir) Arrow(key string) string {
+	if l.Sort != key {
+		return ""
+	} else if l.Order == "desc" {
+		return "▾"
+	}
+	return "▴"
+}
+
+//Row prepares f for the html "row" template
+func (l *listDir) Row(f listFile) listRow {
+	return listRow{f, l.Write, l.Restore, l.DirSizes}
+}
+
+//Prev returns the previous page link, if any
+func (l *listDir) Prev() string {
+	if l.Limit == 0 || l.Offset == 0 {
+		return ""
+	}
+	offset := l.Offset - l.Limit
+	if offset < 0 {
+		offset = 0
+	}
+	return l.Link("offset", strconv.Itoa(offset))
+}
+
+//Next returns the next page link, if any
+func (l *listDir) Next() string {
+	if l.Limit == 0 || !l.more {
+		return ""
+	}
+	return l.Link("offset", strconv.Itoa(l.Offset+l.Limit))
+}
+
+//listBatch is the number of names read from
+//a directory before they are stat'd
+const listBatch = 1024
+
+//readdir lists the entries of dir (path is dir relative
+//to the root) whose names match
+func (s *Handler) readdir(dir, path string, match func(string) bool) ([]listFile, error) {
+	files := []listFile{}
+	err := s.scandir(dir, path, match, func(batch []listFile) bool {
+		files = append(files, batch...)
+		return true
+	})
+	return files, err
+}
+
+//scandir reads the entries of dir in batches, passing each
+//to fn until it returns false
+func (s *Handler) scandir(dir, path string, match func(string) bool, fn func([]listFile) bool) error {
+	//readnames and stat separately so a single failed
+	//stat doesn't cause the directory listing to fail
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("Cannot open directory: %s", err)
 	}
+	defer d.Close()
+	for {
+		names, err := d.Readdirnames(listBatch)
+		if len(names) > 0 {
+			kept := names[:0]
+			for _, n := range names {
+				if n == ".DS_Store" {
+					continue //Nope.
+				}
+				if !match(n) {
+					continue
+				}
+				rel := s.relpath(filepath.ToSlash(filepath.Join(path, n)))
+				if s.hidden(rel) || rel == versionsDir {
+					continue
+				}
+				kept = append(kept, n)
+			}
+			if !fn(s.statAll(dir, path, kept)) {
+				return nil
+			}
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("Cannot list directory: %s", err)
+		}
+	}
+}
 
-	return file1 < file2
+//statAll stats names using a bounded pool of workers
+func (s *Handler) statAll(dir, path string, names []string) []listFile {
+	files := make([]listFile, len(names))
+	workers := s.c.ListWorkers
+	if workers > len(names) {
+		workers = len(names)
+	}
+	next := make(chan int)
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				files[i] = s.stat(dir, path, names[i])
+			}
+		}()
+	}
+	for i := range names {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return files
+}
+
+//stat describes the entry n of dir
+func (s *Handler) stat(dir, path, n string) listFile {
+	lf := listFile{
+		Name: n,
+		Path: "/" + filepath.Join(path, n),
+	}
+	//link to previous versions
+	if s.c.UploadOverwrite == overwriteVersion {
+		rel := s.relpath(filepath.ToSlash(filepath.Join(path, n)))
+		if info, err := os.Stat(filepath.Join(s.c.Directory, versionsDir, filepath.FromSlash(rel))); err == nil && info.IsDir() {
+			lf.Versions = "/" + versionsDir + "/" + rel + "/"
+		}
+	}
+	//attempt to stat
+	if f, err := os.Stat(filepath.Join(dir, n)); err == nil {
+		lf.Accessible = true
+		if f.IsDir() {
+			if l, err := os.Lstat(filepath.Join(dir, n)); err == nil {
+				lf.symlink = l.Mode()&os.ModeSymlink != 0
+			}
+		} else {
+			lf.Size = f.Size()
+		}
+		lf.IsDir = f.IsDir()
+		lf.Mtime = f.ModTime()
+	}
+	return lf
+}
+
+//add accumulates the totals of f
+func (l *listDir) add(f listFile) {
+	if !f.Accessible {
+		return
+	} else if f.IsDir {
+		l.NumDirs++
+	} else {
+		l.NumFiles++
+		l.TotalSize += f.Size
+	}
 }
 
 func (s *Handler) dirlist(w http.ResponseWriter, r *http.Request, dir string) {
@@ -78,57 +387,135 @@ func (s *Handler) dirlist(w http.ResponseWriter, r *http.Request, dir string) {
 	}
 
 	list := &listDir{
-		Path:    path,
-		Parent:  parent,
-		Archive: !s.c.NoArchive,
-		Files:   []listFile{},
+		Path:     path,
+		Parent:   parent,
+		Archive:  !s.c.NoArchive,
+		Write:    s.c.Write,
+		Restore:  s.c.Write && s.c.Trash && filepath.ToSlash(path) == trashDir,
+		Upload:   s.c.Upload,
+		DirSizes: s.c.DirSizes,
+		Files:    []listFile{},
 	}
 
-	//readnames and stat separately so a single failed
-	//stat doesn't cause the directory listing to fail
-	d, err := os.Open(dir)
+	//sorting, filtering and pagination
+	q := r.URL.Query()
+	list.defSort, list.defOrder = s.c.ListSort, s.c.ListOrder
+	list.defStream = s.c.ListStream
+	list.Sort = q.Get("sort")
+	if !listSortKeys[list.Sort] {
+		list.Sort = list.defSort
+	}
+	list.Order = q.Get("order")
+	if list.Order != "asc" && list.Order != "desc" {
+		list.Order = list.defOrder
+	}
+	list.Filter = q.Get("q")
+	match, err := listFilter(list.Filter)
 	if err != nil {
-		w.WriteHeader(500)
-		fmt.Fprintf(w, "Cannot open directory: %s", err)
+		w.WriteHeader(400)
+		fmt.Fprintf(w, "Invalid filter: %s", err)
+		return
+	}
+	if n, err := strconv.Atoi(q.Get("offset")); err == nil && n > 0 {
+		list.Offset = n
+	}
+	if n, err := strconv.Atoi(q.Get("limit")); err == nil && n > 0 {
+		list.Limit = n
+	}
+
+	//recursive listings
+	depth := 1
+	if _, ok := q["recursive"]; ok {
+		depth = s.c.ListMaxDepth
+	}
+	if n, err := strconv.Atoi(q.Get("depth")); err == nil && n > 0 {
+		depth = n
+	}
+	if depth > s.c.ListMaxDepth {
+		depth = s.c.ListMaxDepth
+	}
+	if depth > 1 && s.dirtree(w, r, dir, path, depth, match, list.Sort, list.Order == "desc") {
 		return
 	}
-	names, err := d.Readdirnames(-1)
+
+	//unsorted streaming, by default when configured, though
+	//choosing a sort key implies a sorted listing
+	list.Stream = s.c.ListStream && q.Get("sort") == ""
+	if v, ok := q["stream"]; ok {
+		list.Stream = v[0] != "0" && v[0] != "false"
+	}
+	if subtype, _ := listType(r); subtype == "x-ndjson" || subtype == "ndjson" {
+		list.Stream = true
+	}
+	if list.Stream {
+		list.Sort = ""
+		s.dirstream(w, r, dir, list, match)
+		return
+	}
+
+	var files []listFile
+	var modtime time.Time
+	if s.c.ListCache {
+		//cached entries are shared, filter into a copy
+		var cached []listFile
+		cached, modtime, err = s.cachedir(dir, path)
+		for _, f := range cached {
+			if match(f.Name) {
+				files = append(files, f)
+			}
+		}
+	} else {
+		files, err = s.readdir(dir, path, match)
+	}
 	if err != nil {
 		w.WriteHeader(500)
-		fmt.Fprintf(w, "Cannot list directory: %s", err)
+		fmt.Fprint(w, err)
+		return
+	}
+	//file digests
+	alg := q.Get("checksum")
+	if alg != "" && (s.c.NoChecksum || hashes[alg] == nil) {
+		w.WriteHeader(400)
+		fmt.Fprint(w, "Unknown checksum algorithm")
 		return
 	}
 
-	for _, n := range names {
-		if n == ".DS_Store" {
-			continue //Nope.
+	list.Files = []listFile{}
+	for _, f := range files {
+		list.add(f)
+		//recursive directory sizes
+		if s.c.DirSizes && f.IsDir && f.Accessible && !f.symlink {
+			f.Size, f.NumFiles, f.Pending = s.dirsize(filepath.Join(dir, f.Name))
+			list.Pending = list.Pending || f.Pending
 		}
-		lf := listFile{
-			Name: n,
-			Path: "/" + filepath.Join(path, n),
+		list.Files = append(list.Files, f)
+	}
+
+	sort.Sort(s.byKey(list.Files, list.Sort, list.Order == "desc"))
+
+	//paginate
+	if list.Offset > len(list.Files) {
+		list.Offset = len(list.Files)
+	}
+	list.Files = list.Files[list.Offset:]
+	if list.Limit > 0 && len(list.Files) > list.Limit {
+		list.Files = list.Files[:list.Limit]
+		list.more = true
+	}
+
+	//digests are only computed for the current page
+	for i, f := range list.Files {
+		if alg == "" || !f.Accessible || f.IsDir {
+			continue
 		}
-		//attempt to stat
-		if f, err := os.Stat(filepath.Join(dir, n)); err == nil {
-			lf.Accessible = true
-			var size int64
-			if f.IsDir() {
-				n += "/"
-				list.NumDirs++
-			} else {
-				list.NumFiles++
-				size = f.Size()
-				list.TotalSize += size
+		p := filepath.Join(dir, f.Name)
+		if info, err := os.Stat(p); err == nil {
+			if sum, err := s.digestCache.digest(alg, p, info); err == nil {
+				list.Files[i].Digest = alg + ":" + hex.EncodeToString(sum)
 			}
-			lf.IsDir = f.IsDir()
-			lf.Size = size
-			lf.Mtime = f.ModTime()
 		}
-
-		list.Files = append(list.Files, lf)
 	}
 
-	sort.Sort(byName(list.Files))
-
 	accepts := strings.Split(r.Header.Get("Accept"), ",")
 	buff := &bytes.Buffer{}
 	contype := ""
@@ -161,6 +548,15 @@ func (s *Handler) dirlist(w http.ResponseWriter, r *http.Request, dir string) {
 	}
 
 	w.Header().Set("Content-Type", contype)
+	w.Header().Set("Vary", "Accept")
+	//cached listings support conditional requests
+	if s.c.ListCache && !s.c.NoCache {
+		h := fnv.New64a()
+		h.Write(buff.Bytes())
+		w.Header().Set("ETag", fmt.Sprintf(`"%x"`, h.Sum64()))
+		http.ServeContent(w, r, "", modtime, bytes.NewReader(buff.Bytes()))
+		return
+	}
 	w.WriteHeader(200)
 	w.Write(buff.Bytes())
 }
@@ -0,0 +1,58 @@
+package serve
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestListPagination(t *testing.T) {
+	dir := t.TempDir()
+	for _, n := range []string{"a", "b", "c", "d", "e"} {
+		os.WriteFile(filepath.Join(dir, n), []byte(n), 0644)
+	}
+	h, err := NewHandler(Config{Directory: dir, Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	get := func(query, accept string) string {
+		r := httptest.NewRequest("GET", "/"+query, nil)
+		r.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Body.String()
+	}
+	link := regexp.MustCompile(`href="\?([^"]*)">(?:&laquo; )?(prev|next)`)
+	for _, test := range []struct {
+		query, files, prev, next string
+	}{
+		{"?limit=2", "a b", "", "limit=2&amp;offset=2"},
+		{"?limit=2&offset=2", "c d", "limit=2&amp;offset=0", "limit=2&amp;offset=4"},
+		{"?limit=2&offset=4", "e", "limit=2&amp;offset=2", ""},
+		{"?limit=2&offset=9", "", "limit=2&amp;offset=3", ""},
+		{"?limit=2&offset=-1", "a b", "", "limit=2&amp;offset=2"},
+		{"?offset=3", "d e", "", ""},
+	} {
+		list := struct{ Files []listFile }{}
+		json.Unmarshal([]byte(get(test.query, "application/json")), &list)
+		names := []string{}
+		for _, f := range list.Files {
+			names = append(names, f.Name)
+		}
+		if got := strings.Join(names, " "); got != test.files {
+			t.Errorf("%s: got files %q, want %q", test.query, got, test.files)
+		}
+		links := map[string]string{}
+		for _, m := range link.FindAllStringSubmatch(get(test.query, "text/html"), -1) {
+			links[m[2]] = m[1]
+		}
+		if links["prev"] != test.prev || links["next"] != test.next {
+			t.Errorf("%s: got links %v, want prev %q next %q", test.query, links, test.prev, test.next)
+		}
+	}
+}
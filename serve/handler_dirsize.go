@@ -0,0 +1,93 @@
+package serve
+
+import (
+	"io/fs"
+	"path/filepath"
+)
+
+//dirSizeWorkers is the number of directories
+//which are walked concurrently
+const dirSizeWorkers = 2
+
+//dirSize is the recursive size of a directory, entries
+//are removed when the watcher reports a change within
+type dirSize struct {
+	done  bool
+	size  int64
+	files int
+}
+
+//dirsize returns the recursive size and file count of dir, when
+//not yet known, it is queued and pending is returned instead
+func (s *Handler) dirsize(dir string) (size int64, files int, pending bool) {
+	dir = filepath.Clean(dir)
+	s.sizesMut.Lock()
+	defer s.sizesMut.Unlock()
+	if d, ok := s.sizes[dir]; ok {
+		return d.size, d.files, !d.done
+	}
+	select {
+	case s.sizeQueue <- dir:
+		s.sizes[dir] = &dirSize{}
+	default:
+		//queue is full (or closed), retried on the next listing
+	}
+	return 0, 0, true
+}
+
+//sizeWorker walks queued directories
+func (s *Handler) sizeWorker(queue chan string) {
+	for dir := range queue {
+		s.sizesMut.Lock()
+		d := s.sizes[dir]
+		s.sizesMut.Unlock()
+		if d == nil {
+			continue //changed while queued
+		}
+		size, files := s.walksize(dir)
+		s.sizesMut.Lock()
+		if s.sizes[dir] == d {
+			d.size, d.files, d.done = size, files, true
+		}
+		s.sizesMut.Unlock()
+	}
+}
+
+//walksize sums the files within dir, skipping the internal
+//directories within it, each directory walked is watched so
+//that changes anywhere within invalidate the result
+func (s *Handler) walksize(dir string) (int64, int) {
+	size := int64(0)
+	files := 0
+	filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if p != dir && s.internal(p) {
+				return filepath.SkipDir
+			}
+			s.watch(p)
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+			files++
+		}
+		return nil
+	})
+	return size, files
+}
+
+//unsize removes the sizes of all directories containing p
+func (s *Handler) unsize(p string) {
+	root := filepath.Clean(s.c.Directory)
+	s.sizesMut.Lock()
+	defer s.sizesMut.Unlock()
+	for p = filepath.Clean(p); ; p = filepath.Dir(p) {
+		delete(s.sizes, p)
+		if p == root || p == filepath.Dir(p) {
+			break
+		}
+	}
+}
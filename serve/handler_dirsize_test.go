@@ -0,0 +1,44 @@
+package serve
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDirSizeNested(t *testing.T) {
+	dir := t.TempDir()
+	build := filepath.Join(dir, "build")
+	deep := filepath.Join(build, "deep")
+	os.MkdirAll(deep, 0755)
+	os.WriteFile(filepath.Join(deep, "a"), []byte("a"), 0644)
+	h, err := NewHandler(Config{Directory: dir, Quiet: true, DirSizes: true, Watcher: watchNotify})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	expect := func(size int64, files int) {
+		t.Helper()
+		for i := 0; i < 300; i++ {
+			s, f, pending := h.dirsize(build)
+			if !pending && s == size && f == files {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		s, f, pending := h.dirsize(build)
+		t.Fatalf("got size %d, files %d (pending %v), want %d, %d", s, f, pending, size, files)
+	}
+	expect(1, 1)
+	//changes deep within invalidate the size
+	os.WriteFile(filepath.Join(deep, "b"), []byte("bb"), 0644)
+	expect(3, 2)
+	os.Remove(filepath.Join(deep, "a"))
+	expect(2, 1)
+	//as do changes within new directories
+	os.Mkdir(filepath.Join(deep, "new"), 0755)
+	expect(2, 1)
+	os.WriteFile(filepath.Join(deep, "new", "c"), []byte("ccc"), 0644)
+	expect(5, 2)
+}
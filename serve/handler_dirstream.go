@@ -0,0 +1,106 @@
+package serve
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"html/template"
+	"io"
+	"net/http"
+	"strings"
+)
+
+//dirstream writes the listing of dir as it is read, unsorted, flushing
+//after each batch, instead of buffering the entire directory
+func (s *Handler) dirstream(w http.ResponseWriter, r *http.Request, dir string, list *listDir, match func(string) bool) {
+	flush := func() {}
+	if f, ok := w.(http.Flusher); ok {
+		flush = f.Flush
+	}
+	subtype, contype := listType(r)
+	switch subtype {
+	case "html", "xml":
+	case "json", "x-ndjson", "ndjson":
+		subtype, contype = "ndjson", "application/x-ndjson"
+	default:
+		subtype, contype = "plain", "text/plain"
+	}
+	w.Header().Set("Content-Type", contype)
+	w.WriteHeader(200)
+	//per format writers
+	var enc *xml.Encoder
+	root := xml.StartElement{Name: xml.Name{Local: "listDir"}}
+	write := func(f listFile) {}
+	switch subtype {
+	case "html":
+		dirlistHtmlTempl.ExecuteTemplate(w, "head", list)
+		write = func(f listFile) {
+			dirlistHtmlTempl.ExecuteTemplate(w, "row", list.Row(f))
+		}
+	case "xml":
+		enc = xml.NewEncoder(w)
+		enc.EncodeToken(root)
+		enc.EncodeElement(list.Path, xml.StartElement{Name: xml.Name{Local: "Path"}})
+		write = func(f listFile) {
+			enc.EncodeElement(f, xml.StartElement{Name: xml.Name{Local: "Files"}})
+		}
+	case "ndjson":
+		write = func(f listFile) {
+			b, _ := json.Marshal(f)
+			w.Write(append(b, '\n'))
+		}
+	default:
+		write = func(f listFile) {
+			io.WriteString(w, f.Name+"\n")
+		}
+	}
+	flush()
+	skip := list.Offset
+	written := 0
+	err := s.scandir(dir, list.Path, match, func(batch []listFile) bool {
+		for _, f := range batch {
+			list.add(f)
+			if skip > 0 {
+				skip--
+				continue
+			}
+			if list.Limit > 0 && written == list.Limit {
+				list.more = true
+				return false
+			}
+			write(f)
+			written++
+		}
+		if enc != nil {
+			enc.Flush()
+		}
+		flush()
+		return true
+	})
+	switch subtype {
+	case "html":
+		if err != nil {
+			io.WriteString(w, "<p>"+template.HTMLEscapeString(err.Error())+"</p>")
+		}
+		dirlistHtmlTempl.ExecuteTemplate(w, "foot", list)
+	case "xml":
+		enc.EncodeToken(root.End())
+		enc.Flush()
+	}
+}
+
+//listType returns the subtype and full content type
+//of the first supported type in the Accept header
+func listType(r *http.Request) (string, string) {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		accept = strings.TrimSpace(strings.SplitN(accept, ";", 2)[0])
+		typeencoding := strings.SplitN(accept, "/", 2)
+		if len(typeencoding) != 2 {
+			continue
+		}
+		switch typeencoding[1] {
+		case "json", "xml", "html", "x-ndjson", "ndjson":
+			return typeencoding[1], accept
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,68 @@
+package serve
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestListStream(t *testing.T) {
+	dir := t.TempDir()
+	for _, n := range []string{"a", "b", "c", "d", "e"} {
+		os.WriteFile(filepath.Join(dir, n), []byte(n), 0644)
+	}
+	for _, test := range []struct {
+		stream        bool
+		query, accept string
+		contype       string
+		lines         int
+	}{
+		{false, "?stream=1", "text/plain", "text/plain", 5},
+		{false, "?stream=1&limit=2", "text/plain", "text/plain", 2},
+		{false, "?stream=1&limit=2&offset=4", "text/plain", "text/plain", 1},
+		{false, "?stream=1", "application/json", "application/x-ndjson", 5},
+		{true, "", "application/x-ndjson", "application/x-ndjson", 5},
+		{true, "?stream=0", "application/json", "application/json", 0},
+		{true, "?sort=name", "application/json", "application/json", 0},
+	} {
+		h, err := NewHandler(Config{Directory: dir, Quiet: true, ListStream: test.stream})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest("GET", "/"+test.query, nil)
+		r.Header.Set("Accept", test.accept)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		h.Close()
+		if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, test.contype) {
+			t.Errorf("%s %s: got content type %q, want %q", test.query, test.accept, got, test.contype)
+		}
+		if test.lines == 0 {
+			continue //buffered
+		}
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		if len(lines) != test.lines {
+			t.Errorf("%s %s: got %d lines, want %d: %q", test.query, test.accept, len(lines), test.lines, w.Body)
+		}
+		if test.contype != "application/x-ndjson" {
+			continue
+		}
+		//every line is an entry, together they're the full directory
+		names := []string{}
+		for _, line := range lines {
+			f := listFile{}
+			if err := json.Unmarshal([]byte(line), &f); err != nil {
+				t.Fatalf("invalid ndjson line %q: %s", line, err)
+			}
+			names = append(names, f.Name)
+		}
+		sort.Strings(names)
+		if got := strings.Join(names, " "); got != "a b c d e" {
+			t.Errorf("%s %s: got entries %q", test.query, test.accept, got)
+		}
+	}
+}
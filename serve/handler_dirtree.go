@@ -0,0 +1,165 @@
+package serve
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//treeWalker streams a recursive directory listing,
+//stopping after a maximum number of entries
+type treeWalker struct {
+	s         *Handler
+	match     func(string) bool
+	key       string
+	desc      bool
+	depth     int
+	remaining int
+	truncated bool
+	flush     func()
+}
+
+//dirtree writes a recursive listing of dir as a nested tree (json and
+//xml) or a flat list of relative paths (plain text), returns false
+//when html is preferred, which is not available recursively
+func (s *Handler) dirtree(w http.ResponseWriter, r *http.Request, dir, path string, depth int, match func(string) bool, key string, desc bool) bool {
+	subtype, contype := listType(r)
+	switch subtype {
+	case "html":
+		return false
+	case "json", "xml":
+	default:
+		contype = "text/plain"
+	}
+	t := &treeWalker{
+		s:         s,
+		match:     match,
+		key:       key,
+		desc:      desc,
+		depth:     depth,
+		remaining: s.c.ListMaxEntries,
+		flush:     func() {},
+	}
+	if f, ok := w.(http.Flusher); ok {
+		t.flush = f.Flush
+	}
+	w.Header().Set("Content-Type", contype)
+	w.Header().Set("Trailer", "Serve-Truncated")
+	w.WriteHeader(200)
+	switch {
+	case strings.HasSuffix(contype, "json"):
+		p, _ := json.Marshal(path)
+		io.WriteString(w, `{"Path":`+string(p)+`,"Depth":`+strconv.Itoa(depth)+`,"Files":`)
+		t.json(w, dir, path, 1)
+		io.WriteString(w, `,"Truncated":`+strconv.FormatBool(t.truncated)+"}\n")
+	case strings.HasSuffix(contype, "xml"):
+		enc := xml.NewEncoder(w)
+		root := xml.StartElement{Name: xml.Name{Local: "listDir"}}
+		enc.EncodeToken(root)
+		enc.EncodeElement(path, xml.StartElement{Name: xml.Name{Local: "Path"}})
+		enc.EncodeElement(depth, xml.StartElement{Name: xml.Name{Local: "Depth"}})
+		t.xml(enc, dir, path, 1)
+		enc.EncodeElement(t.truncated, xml.StartElement{Name: xml.Name{Local: "Truncated"}})
+		enc.EncodeToken(root.End())
+		enc.Flush()
+	default:
+		t.plain(w, dir, path, "", 1)
+	}
+	w.Header().Set("Serve-Truncated", strconv.FormatBool(t.truncated))
+	return true
+}
+
+//entries reads and sorts the entries of dir, directories
+//are always included, files only when they match
+func (t *treeWalker) entries(dir, path string) []listFile {
+	files, err := t.s.readdir(dir, path, func(string) bool { return true })
+	if err != nil {
+		return nil //unreadable directories are listed as empty
+	}
+	kept := files[:0]
+	for _, f := range files {
+		if f.IsDir || t.match(f.Name) {
+			kept = append(kept, f)
+		}
+	}
+	sort.Sort(t.s.byKey(kept, t.key, t.desc))
+	if len(kept) > t.remaining {
+		kept = kept[:t.remaining]
+		t.truncated = true
+	}
+	t.remaining -= len(kept)
+	return kept
+}
+
+//descend reports whether f should be walked, symlinked
+//directories are never followed to prevent cycles
+func (t *treeWalker) descend(f listFile, level int) bool {
+	return f.IsDir && f.Accessible && !f.symlink && level < t.depth && !t.truncated
+}
+
+func (t *treeWalker) plain(w io.Writer, dir, path, prefix string, level int) {
+	for _, f := range t.entries(dir, path) {
+		name := prefix + f.Name
+		if f.IsDir {
+			name += "/"
+		}
+		io.WriteString(w, name+"\n")
+		if t.descend(f, level) {
+			t.plain(w, filepath.Join(dir, f.Name), filepath.Join(path, f.Name), name, level+1)
+		}
+	}
+	t.flush()
+}
+
+func (t *treeWalker) json(w io.Writer, dir, path string, level int) {
+	io.WriteString(w, "[")
+	for i, f := range t.entries(dir, path) {
+		if i > 0 {
+			io.WriteString(w, ",")
+		}
+		b, _ := json.Marshal(f)
+		if !t.descend(f, level) {
+			w.Write(b)
+			continue
+		}
+		//reopen the object to nest its entries
+		w.Write(b[:len(b)-1])
+		io.WriteString(w, `,"Files":`)
+		t.json(w, filepath.Join(dir, f.Name), filepath.Join(path, f.Name), level+1)
+		io.WriteString(w, "}")
+	}
+	io.WriteString(w, "]")
+	t.flush()
+}
+
+//xml matches the non-recursive listing, where each
+//entry is a <Files> element, directories nest theirs
+func (t *treeWalker) xml(enc *xml.Encoder, dir, path string, level int) {
+	for _, f := range t.entries(dir, path) {
+		start := xml.StartElement{Name: xml.Name{Local: "Files"}}
+		enc.EncodeToken(start)
+		field := func(name string, v interface{}) {
+			enc.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: name}})
+		}
+		field("Path", f.Path)
+		field("Name", f.Name)
+		field("Accessible", f.Accessible)
+		field("IsDir", f.IsDir)
+		field("Size", f.Size)
+		field("Mtime", f.Mtime)
+		field("Versions", f.Versions)
+		field("NumFiles", f.NumFiles)
+		field("Pending", f.Pending)
+		if t.descend(f, level) {
+			t.xml(enc, filepath.Join(dir, f.Name), filepath.Join(path, f.Name), level+1)
+		}
+		enc.EncodeToken(start.End())
+	}
+	enc.Flush()
+	t.flush()
+}
@@ -0,0 +1,71 @@
+package serve
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListTree(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "a", "b", "c"), 0755)
+	for _, f := range []string{"a/b/c/f", "a/x", "top"} {
+		os.WriteFile(filepath.Join(dir, filepath.FromSlash(f)), []byte(f), 0644)
+	}
+	for _, test := range []struct {
+		config          Config
+		query, accept   string
+		want, truncated string
+	}{
+		{Config{}, "?depth=2", "text/plain", "a/\na/b/\na/x\ntop\n", "false"},
+		{Config{}, "?recursive", "text/plain", "a/\na/b/\na/b/c/\na/b/c/f\na/x\ntop\n", "false"},
+		{Config{ListMaxDepth: 3}, "?depth=9", "text/plain", "a/\na/b/\na/b/c/\na/x\ntop\n", "false"},
+		{Config{ListMaxEntries: 3}, "?recursive", "text/plain", "a/\na/b/\ntop\n", "true"},
+		{Config{}, "?depth=2&q=x", "text/plain", "a/\na/b/\na/x\n", "false"},
+	} {
+		c := test.config
+		c.Directory, c.Quiet = dir, true
+		h, err := NewHandler(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest("GET", "/"+test.query, nil)
+		r.Header.Set("Accept", test.accept)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		h.Close()
+		res := w.Result()
+		if got := w.Body.String(); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.query, got, test.want)
+		}
+		if got := res.Trailer.Get("Serve-Truncated"); got != test.truncated {
+			t.Errorf("%s: got truncated trailer %q, want %q", test.query, got, test.truncated)
+		}
+	}
+	//json nests the entries of each directory
+	h, err := NewHandler(Config{Directory: dir, Quiet: true, ListMaxEntries: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	r := httptest.NewRequest("GET", "/?recursive", nil)
+	r.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	type entry struct {
+		Name  string
+		Files []entry
+	}
+	tree := struct {
+		Files     []entry
+		Truncated bool
+	}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &tree); err != nil {
+		t.Fatalf("invalid json: %s %q", err, w.Body)
+	}
+	if !tree.Truncated || len(tree.Files) != 2 || len(tree.Files[0].Files) != 1 || tree.Files[0].Files[0].Name != "b" {
+		t.Errorf("unexpected tree: %+v", tree)
+	}
+}
@@ -0,0 +1,188 @@
+package serve
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+//fallbackTriggers decide which requests are sent to the fallback
+type fallbackTriggers struct {
+	missing, dir bool
+	statuses     map[int]bool
+	methods      map[string]bool
+	notMethods   map[string]bool
+	globs        []string
+}
+
+//parseFallbackTriggers parses 'missing', 'dir', 'status:CODE',
+//'method:METHOD', 'method:!METHOD' and path globs ('/api/*'),
+//defaulting to missing files and directories
+func parseFallbackTriggers(triggers []string) (*fallbackTriggers, error) {
+	if len(triggers) == 0 {
+		triggers = []string{"missing", "dir"}
+	}
+	t := &fallbackTriggers{
+		statuses:   map[int]bool{},
+		methods:    map[string]bool{},
+		notMethods: map[string]bool{},
+	}
+	for _, trigger := range triggers {
+		switch {
+		case trigger == "missing":
+			t.missing = true
+		case trigger == "dir":
+			t.dir = true
+		case strings.HasPrefix(trigger, "status:"):
+			code, err := strconv.Atoi(strings.TrimPrefix(trigger, "status:"))
+			if err != nil || code < 100 || code > 599 {
+				return nil, fmt.Errorf("Invalid fallback status trigger: %s", trigger)
+			}
+			t.statuses[code] = true
+		case strings.HasPrefix(trigger, "method:!"):
+			t.notMethods[strings.ToUpper(strings.TrimPrefix(trigger, "method:!"))] = true
+		case strings.HasPrefix(trigger, "method:"):
+			t.methods[strings.ToUpper(strings.TrimPrefix(trigger, "method:"))] = true
+		case strings.HasPrefix(trigger, "/"):
+			if _, err := path.Match(trigger, ""); err != nil {
+				return nil, fmt.Errorf("Invalid fallback path trigger: %s", trigger)
+			}
+			t.globs = append(t.globs, trigger)
+		default:
+			return nil, fmt.Errorf("Invalid fallback trigger: %s (must be missing, dir, status:CODE, method:METHOD or a path glob)", trigger)
+		}
+	}
+	return t, nil
+}
+
+//match reports whether r is sent to the fallback, given the
+//state of the file it requested (statuses are matched once the
+//response is known, by an interceptWriter)
+func (t *fallbackTriggers) match(r *http.Request, missing, isdir bool) bool {
+	if (t.missing && missing) || (t.dir && isdir) || t.methods[r.Method] {
+		return true
+	}
+	if len(t.notMethods) > 0 && !t.notMethods[r.Method] {
+		return true
+	}
+	//globs match the path or any of its parents
+	for p := path.Clean(r.URL.Path); ; p = path.Dir(p) {
+		for _, glob := range t.globs {
+			if ok, _ := path.Match(glob, p); ok {
+				return true
+			}
+		}
+		if p == "/" || p == "." {
+			return false
+		}
+	}
+}
+
+//serveFallback sends r to the fallback, bypassing any interceptWriter
+//which w may be, so that a trigger status returned by the fallback
+//itself isn't intercepted (sending r to the fallback again)
+func (s *Handler) serveFallback(w http.ResponseWriter, r *http.Request) {
+	if iw, ok := w.(*interceptWriter); ok {
+		iw.wroteHeader = true
+		w = iw.w
+	}
+	s.fallback.ServeHTTP(w, r)
+}
+
+//fallbackMaxRetry is the largest request body kept to be
+//served again, when the fallback first responds with a 404
+const fallbackMaxRetry = 8 << 20
+
+//bufferBody reads the body of r, up to max bytes, and replaces it
+//so that it can be read again, returns false when the body is larger,
+//in which case its start is put back in front of the remainder
+func bufferBody(r *http.Request, max int64) ([]byte, bool, error) {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return nil, true, nil
+	}
+	if r.ContentLength > max {
+		return nil, false, nil
+	}
+	b, err := io.ReadAll(io.LimitReader(r.Body, max+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(b)) > max {
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(b), r.Body), r.Body}
+		return nil, false, nil
+	}
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(b))
+	return b, true, nil
+}
+
+//interceptWriter holds the headers of a response until its status
+//is known, responses with one of the codes are discarded instead
+//of written, so that another handler can reply
+type interceptWriter struct {
+	w           http.ResponseWriter
+	codes       map[int]bool
+	header      http.Header
+	wroteHeader bool
+	intercepted bool
+}
+
+func newInterceptWriter(w http.ResponseWriter, codes map[int]bool) *interceptWriter {
+	return &interceptWriter{w: w, codes: codes, header: http.Header{}}
+}
+
+func (i *interceptWriter) Header() http.Header {
+	return i.header
+}
+
+func (i *interceptWriter) WriteHeader(code int) {
+	if i.wroteHeader {
+		return
+	}
+	i.wroteHeader = true
+	if i.codes[code] {
+		i.intercepted = true
+		return
+	}
+	h := i.w.Header()
+	for k, v := range i.header {
+		h[k] = v
+	}
+	i.w.WriteHeader(code)
+}
+
+func (i *interceptWriter) Write(b []byte) (int, error) {
+	if !i.wroteHeader {
+		i.WriteHeader(200)
+	}
+	if i.intercepted {
+		return len(b), nil
+	}
+	return i.w.Write(b)
+}
+
+func (i *interceptWriter) Flush() {
+	if !i.wroteHeader || i.intercepted {
+		return
+	}
+	if f, ok := i.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (i *interceptWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := i.w.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("Hijack not supported")
+	}
+	return hj.Hijack()
+}
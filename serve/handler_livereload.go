@@ -0,0 +1,326 @@
+package serve
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jpillora/serve/serve/static"
+)
+
+//bodyClose is the tag which the LiveReload script is inserted before
+const bodyClose = "</body>"
+
+//live reload paths, sharing the main listener
+const (
+	lrClientPath = "/__serve/livereload.js"
+	lrEventsPath = "/__serve/events"
+)
+
+//lrScript loads the live reload client
+var lrScript = []byte(`<script src="` + lrClientPath + `"></script>`)
+
+//lrKeepAlive is the interval between comments sent
+//to idle event streams, so proxies keep them open
+const lrKeepAlive = 30 * time.Second
+
+//live reload actions, clients swap stylesheets and
+//images in place, anything else reloads the page
+const (
+	lrReload = "reload"
+	lrCSS    = "css"
+	lrImg    = "img"
+)
+
+//lrSwapDefaults are the extensions swapped in place by default
+var lrSwapDefaults = []string{
+	".css=css",
+	".png=img", ".jpg=img", ".jpeg=img", ".gif=img",
+	".svg=img", ".webp=img", ".avif=img", ".ico=img",
+}
+
+//lrSwaps parses ext=action rules into a map, later rules win
+func lrSwaps(rules []string) (map[string]string, error) {
+	swaps := map[string]string{}
+	for _, rule := range rules {
+		kv := strings.SplitN(rule, "=", 2)
+		if len(kv) != 2 || !strings.HasPrefix(kv[0], ".") {
+			return nil, fmt.Errorf("Invalid live reload swap rule: %s (must be in the form '.ext=action')", rule)
+		}
+		switch kv[1] {
+		case lrReload, lrCSS, lrImg:
+		default:
+			return nil, fmt.Errorf("Invalid live reload swap action: %s (must be css, img or reload)", kv[1])
+		}
+		swaps[strings.ToLower(kv[0])] = kv[1]
+	}
+	return swaps, nil
+}
+
+//changed queues a reload for p, once no further changes
+//occur within the delay, all queued changes are sent
+func (s *Handler) changed(p string) {
+	if s.ctx.Err() != nil {
+		return //closed
+	}
+	s.lrMut.Lock()
+	s.lrPending[p] = time.Now()
+	if s.lrTimer == nil {
+		s.lrTimer = time.AfterFunc(s.c.LiveReloadDelay, s.flushChanges)
+	} else {
+		s.lrTimer.Reset(s.c.LiveReloadDelay)
+	}
+	s.lrMut.Unlock()
+}
+
+//flushChanges runs the matching on change commands, then sends
+//the queued changes, a single page reload when any of them requires
+//one. Changes queued while the commands ran are their own output,
+//and are reloaded without running the commands again
+func (s *Handler) flushChanges() {
+	s.lrFlushMut.Lock()
+	defer s.lrFlushMut.Unlock()
+	if s.ctx.Err() != nil {
+		return //closed
+	}
+	s.lrMut.Lock()
+	pending := s.lrPending
+	s.lrPending = map[string]time.Time{}
+	s.lrMut.Unlock()
+	changes := make([]string, 0, len(pending))
+	edits := []string{}
+	for p, t := range pending {
+		changes = append(changes, p)
+		if t.After(s.lrRanUntil) {
+			edits = append(edits, p)
+		}
+	}
+	if rules := s.changeRules(edits); len(rules) > 0 {
+		err := s.runChangeRules(rules)
+		//their output is reported within the delay, or
+		//once the next scan finds it when polling
+		settle := s.c.LiveReloadDelay
+		if _, poll := s.watcher.(*pollWatcher); poll {
+			settle += s.c.PollInterval
+		}
+		s.lrRanUntil = time.Now().Add(settle)
+		if err != nil {
+			s.failure(err.Error())
+			return
+		}
+	}
+	sort.Strings(changes)
+	for _, p := range changes {
+		if s.lrAction(p) == lrReload {
+			changes = []string{p}
+			break
+		}
+	}
+	for _, p := range changes {
+		s.reload(p)
+	}
+}
+
+//lrAction returns the action clients take when p changes
+func (s *Handler) lrAction(p string) string {
+	if action, ok := s.lrSwaps[strings.ToLower(filepath.Ext(p))]; ok {
+		return action
+	}
+	return lrReload
+}
+
+//reload notifies all live reload clients that the file p changed
+func (s *Handler) reload(p string) {
+	if s.lr != nil {
+		s.lr.Reload(p)
+	}
+	rel, err := filepath.Rel(s.c.Directory, p)
+	if err != nil {
+		return
+	}
+	b, _ := json.Marshal(struct {
+		Path   string `json:"path"`
+		Action string `json:"action"`
+	}{"/" + filepath.ToSlash(rel), s.lrAction(p)})
+	s.broadcast("change", string(b))
+}
+
+//failure shows msg to all live reload clients, instead of reloading
+func (s *Handler) failure(msg string) {
+	if s.lr != nil {
+		s.lr.Alert(msg)
+	}
+	s.broadcast("failure", msg)
+}
+
+//broadcast sends an event to all live reload clients
+func (s *Handler) broadcast(event, data string) {
+	msg := "event: " + event + "\n"
+	for _, line := range strings.Split(data, "\n") {
+		msg += "data: " + line + "\n"
+	}
+	msg += "\n"
+	s.lrMut.Lock()
+	for c := range s.lrClients {
+		select {
+		case c <- msg:
+		default:
+			//client is behind, drop the event
+		}
+	}
+	s.lrMut.Unlock()
+}
+
+//lrClient serves the injected live reload client
+func (s *Handler) lrClient(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/javascript")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(static.MustAsset("static/livereload.js"))
+}
+
+//lrEvents streams change events to a live reload client (Server-Sent Events)
+func (s *Handler) lrEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(500)
+		w.Write([]byte("Streaming not supported"))
+		return
+	}
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(200)
+	io.WriteString(w, "retry: 1000\n\n")
+	flusher.Flush()
+	c := make(chan string, 16)
+	s.lrMut.Lock()
+	s.lrClients[c] = true
+	s.lrMut.Unlock()
+	defer func() {
+		s.lrMut.Lock()
+		delete(s.lrClients, c)
+		s.lrMut.Unlock()
+	}()
+	keepAlive := time.NewTicker(lrKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.ctx.Done():
+			return
+		case msg := <-c:
+			io.WriteString(w, msg)
+		case <-keepAlive.C:
+			io.WriteString(w, ": keep-alive\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+//injectWriter inserts script into HTML responses, before the
+//first </body> (or at the end), while they're being written
+type injectWriter struct {
+	http.ResponseWriter
+	script      []byte
+	held        []byte
+	inject      bool
+	injected    bool
+	wroteHeader bool
+}
+
+//injector wraps w with the LiveReload script injector,
+//close must be called once the response is complete
+func (s *Handler) injector(w http.ResponseWriter) *injectWriter {
+	return &injectWriter{ResponseWriter: w, script: lrScript}
+}
+
+func (i *injectWriter) WriteHeader(code int) {
+	if i.wroteHeader {
+		return
+	}
+	i.wroteHeader = true
+	h := i.Header()
+	//only complete, uncompressed html is modified
+	if code == 200 && strings.HasPrefix(h.Get("Content-Type"), "text/html") && h.Get("Content-Encoding") == "" {
+		i.inject = true
+		if n, err := strconv.ParseInt(h.Get("Content-Length"), 10, 64); err == nil {
+			h.Set("Content-Length", strconv.FormatInt(n+int64(len(i.script)), 10))
+		}
+		//the body no longer matches the file
+		h.Del("Accept-Ranges")
+		h.Del("Repr-Digest")
+		h.Del("Content-Digest")
+	}
+	i.ResponseWriter.WriteHeader(code)
+}
+
+func (i *injectWriter) Write(b []byte) (int, error) {
+	if !i.wroteHeader {
+		i.WriteHeader(200)
+	}
+	if !i.inject || i.injected {
+		return i.ResponseWriter.Write(b)
+	}
+	n := len(b)
+	buf := append(i.held, b...)
+	if idx := indexBodyClose(buf); idx >= 0 {
+		i.injected = true
+		i.held = nil
+		if _, err := i.ResponseWriter.Write(buf[:idx]); err != nil {
+			return 0, err
+		}
+		if _, err := i.ResponseWriter.Write(i.script); err != nil {
+			return 0, err
+		}
+		if _, err := i.ResponseWriter.Write(buf[idx:]); err != nil {
+			return 0, err
+		}
+		return n, nil
+	}
+	//hold back a possible partial tag
+	keep := len(bodyClose) - 1
+	if keep > len(buf) {
+		keep = len(buf)
+	}
+	if _, err := i.ResponseWriter.Write(buf[:len(buf)-keep]); err != nil {
+		return 0, err
+	}
+	i.held = append([]byte(nil), buf[len(buf)-keep:]...)
+	return n, nil
+}
+
+func (i *injectWriter) Flush() {
+	if f, ok := i.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+//close writes any held bytes, and the script
+//when the document had no </body>
+func (i *injectWriter) close() {
+	if !i.inject || i.injected {
+		return
+	}
+	i.injected = true
+	i.ResponseWriter.Write(i.held)
+	i.ResponseWriter.Write(i.script)
+}
+
+//indexBodyClose finds the first </body>, ignoring case
+func indexBodyClose(b []byte) int {
+	for i := 0; i+len(bodyClose) <= len(b); i++ {
+		if b[i] == '<' && bytes.EqualFold(b[i:i+len(bodyClose)], []byte(bodyClose)) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,251 @@
+package serve
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInjectWriter(t *testing.T) {
+	script := []byte("<script></script>")
+	for _, test := range []struct {
+		contype string
+		chunks  []string
+		want    string
+	}{
+		{"text/html; charset=utf-8", []string{"<p>a</p></body></html>"}, "<p>a</p><script></script></body></html>"},
+		{"text/html; charset=utf-8", []string{"<p>a</p></BO", "DY></html>"}, "<p>a</p><script></script></BODY></html>"},
+		{"text/html", []string{"<p>a</p>"}, "<p>a</p><script></script>"},
+		{"text/css", []string{"body{}</body>"}, "body{}</body>"},
+	} {
+		body := 0
+		for _, c := range test.chunks {
+			body += len(c)
+		}
+		rec := httptest.NewRecorder()
+		rec.Header().Set("Content-Type", test.contype)
+		rec.Header().Set("Content-Length", strconv.Itoa(body))
+		iw := &injectWriter{ResponseWriter: rec, script: script}
+		iw.WriteHeader(200)
+		for _, c := range test.chunks {
+			iw.Write([]byte(c))
+		}
+		iw.close()
+		if got := rec.Body.String(); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.contype, got, test.want)
+		}
+		if cl := rec.Header().Get("Content-Length"); cl != strconv.Itoa(len(test.want)) {
+			t.Errorf("%s: content length %s, body %d", test.contype, cl, len(test.want))
+		}
+	}
+}
+
+//lrEvent is a received Server-Sent Event
+type lrEvent struct {
+	event, data string
+}
+
+//lrListen connects to the event stream of h, returning received events
+func lrListen(t *testing.T, h http.Handler) <-chan lrEvent {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+lrEventsPath, nil)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != 200 || res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected event stream response: %d %s", res.StatusCode, res.Header.Get("Content-Type"))
+	}
+	br := bufio.NewReader(res.Body)
+	if line, _ := br.ReadString('\n'); line != "retry: 1000\n" {
+		t.Fatalf("expected retry first, got %q", line)
+	}
+	events := make(chan lrEvent, 16)
+	go func() {
+		defer res.Body.Close()
+		e := lrEvent{}
+		data := []string{}
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil {
+				close(events)
+				return
+			}
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case line == "":
+				if e.event != "" {
+					e.data = strings.Join(data, "\n")
+					events <- e
+				}
+				e, data = lrEvent{}, []string{}
+			case strings.HasPrefix(line, "event: "):
+				e.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				data = append(data, strings.TrimPrefix(line, "data: "))
+			}
+		}
+	}()
+	return events
+}
+
+//lrExpect receives the next event, or fails after a timeout
+func lrExpect(t *testing.T, events <-chan lrEvent) lrEvent {
+	t.Helper()
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(3 * time.Second):
+		t.Fatal("expected an event, got nothing")
+	}
+	return lrEvent{}
+}
+
+//lrWatching waits until dir is being watched
+func lrWatching(t *testing.T, h *Handler, dir string) {
+	t.Helper()
+	for i := 0; i < 300; i++ {
+		h.watcherMut.Lock()
+		ok := h.watching[dir]
+		h.watcherMut.Unlock()
+		if ok {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%s is not watched", dir)
+}
+
+func TestLiveReloadEvents(t *testing.T) {
+	dir := t.TempDir()
+	h, err := NewHandler(Config{Directory: dir, Quiet: true, LiveReload: true, Watcher: watchNotify})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	events := lrListen(t, h)
+	lrWatching(t, h, dir)
+	os.WriteFile(filepath.Join(dir, "index.html"), []byte("<p>a</p>"), 0644)
+	e := lrExpect(t, events)
+	if e.event != "change" || e.data != `{"path":"/index.html","action":"reload"}` {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+	//multi-line data is sent as one event
+	h.broadcast("failure", "make: failed\n\nerror")
+	e = lrExpect(t, events)
+	if e.event != "failure" || e.data != "make: failed\n\nerror" {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+	//closing ends the stream
+	h.Close()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Fatal("unexpected event after close")
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("event stream still open after close")
+	}
+}
+
+func TestLiveReloadSwaps(t *testing.T) {
+	if _, err := lrSwaps([]string{"css=css"}); err == nil {
+		t.Error("expected an error for a rule without a dot")
+	}
+	if _, err := lrSwaps([]string{".js=js"}); err == nil {
+		t.Error("expected an error for an unknown action")
+	}
+	dir := t.TempDir()
+	h, err := NewHandler(Config{
+		Directory:      dir,
+		Quiet:          true,
+		LiveReload:     true,
+		LiveReloadSwap: []string{".scss=css", ".svg=reload"},
+		Watcher:        watchNotify,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	for p, want := range map[string]string{
+		"a.css":      lrCSS,
+		"a.SCSS":     lrCSS,
+		"a.png":      lrImg,
+		"a.svg":      lrReload,
+		"index.html": lrReload,
+		"Makefile":   lrReload,
+	} {
+		if got := h.lrAction(filepath.Join(dir, p)); got != want {
+			t.Errorf("%s: got %s, want %s", p, got, want)
+		}
+	}
+	events := lrListen(t, h)
+	lrWatching(t, h, dir)
+	//swaps are sent individually
+	os.WriteFile(filepath.Join(dir, "a.css"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(dir, "b.png"), []byte("b"), 0644)
+	got := []string{lrExpect(t, events).data, lrExpect(t, events).data}
+	sort.Strings(got)
+	want := []string{`{"path":"/a.css","action":"css"}`, `{"path":"/b.png","action":"img"}`}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestLiveReloadWatch(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "node_modules", "x"), 0755)
+	os.MkdirAll(filepath.Join(dir, "dist"), 0755)
+	h, err := NewHandler(Config{
+		Directory:        dir,
+		Quiet:            true,
+		LiveReload:       true,
+		LiveReloadIgnore: []string{"*.tmp", "dist"},
+		LiveReloadDelay:  200 * time.Millisecond,
+		Watcher:          watchNotify,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	events := lrListen(t, h)
+	lrWatching(t, h, dir)
+	h.watcherMut.Lock()
+	if h.watching[filepath.Join(dir, "node_modules", "x")] || h.watching[filepath.Join(dir, "dist")] {
+		t.Error("ignored directories are watched")
+	}
+	h.watcherMut.Unlock()
+	//new directories are watched as they're created
+	sub := filepath.Join(dir, "sub")
+	os.Mkdir(sub, 0755)
+	if e := lrExpect(t, events); e.data != `{"path":"/sub","action":"reload"}` {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+	lrWatching(t, h, sub)
+	//ignored changes are not sent, a burst of changes
+	//is a single reload, replacing the swaps
+	os.WriteFile(filepath.Join(dir, "a.tmp"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(sub, "a.css"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(sub, "b.html"), []byte("b"), 0644)
+	os.WriteFile(filepath.Join(sub, "c.css"), []byte("c"), 0644)
+	if e := lrExpect(t, events); e.data != `{"path":"/sub/b.html","action":"reload"}` {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+	select {
+	case e := <-events:
+		t.Fatalf("unexpected event: %+v", e)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
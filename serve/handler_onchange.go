@@ -0,0 +1,105 @@
+package serve
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+//changeOutputSize is the amount of command
+//output shown in the browser on failure
+const changeOutputSize = 16 * 1024
+
+//changeRule runs cmd when a file matching glob changes
+type changeRule struct {
+	glob, cmd string
+}
+
+//parseChangeRules parses 'cmd' and 'glob=cmd' rules, the glob must
+//not contain spaces, otherwise the rule is treated as a command
+func parseChangeRules(rules []string) ([]changeRule, error) {
+	parsed := []changeRule{}
+	for _, rule := range rules {
+		r := changeRule{cmd: rule}
+		if kv := strings.SplitN(rule, "=", 2); len(kv) == 2 && kv[0] != "" && !strings.ContainsAny(kv[0], " \t") {
+			if _, err := path.Match(kv[0], ""); err != nil {
+				return nil, fmt.Errorf("Invalid on change glob: %s", kv[0])
+			}
+			r.glob, r.cmd = kv[0], kv[1]
+		}
+		if strings.TrimSpace(r.cmd) == "" {
+			return nil, fmt.Errorf("Invalid on change rule: %s (missing command)", rule)
+		}
+		parsed = append(parsed, r)
+	}
+	return parsed, nil
+}
+
+//changeRules returns the rules matching any of the changed files
+func (s *Handler) changeRules(changes []string) []changeRule {
+	rules := []changeRule{}
+	for _, r := range s.onChange {
+		for _, p := range changes {
+			rel, err := filepath.Rel(s.c.Directory, p)
+			if err != nil {
+				continue
+			}
+			if r.glob == "" || globMatch(r.glob, filepath.ToSlash(rel)) {
+				rules = append(rules, r)
+				break
+			}
+		}
+	}
+	return rules
+}
+
+//runChangeRules runs each command in turn from the root directory,
+//output is streamed to the console, the first failure is returned
+//along with the end of its output, commands are killed by Close
+func (s *Handler) runChangeRules(rules []changeRule) error {
+	for _, r := range rules {
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.CommandContext(s.ctx, "cmd", "/C", r.cmd)
+		} else {
+			cmd = exec.CommandContext(s.ctx, "sh", "-c", r.cmd)
+		}
+		cmd.Dir = s.c.Directory
+		//stdout and stderr share a writer (and a pipe)
+		out := &tailBuffer{max: changeOutputSize}
+		w := io.Writer(out)
+		if !s.c.Quiet {
+			fmt.Printf("on change: %s\n", r.cmd)
+			w = io.MultiWriter(os.Stdout, out)
+		}
+		cmd.Stdout = w
+		cmd.Stderr = w
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s: %s\n\n%s", r.cmd, err, out.b)
+		}
+	}
+	return nil
+}
+
+//tailBuffer keeps the last max bytes written to it
+type tailBuffer struct {
+	max int
+	b   []byte
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if len(p) > t.max {
+		p = p[len(p)-t.max:]
+	}
+	if over := len(t.b) + len(p) - t.max; over > 0 {
+		t.b = append(t.b[:0], t.b[over:]...)
+	}
+	t.b = append(t.b, p...)
+	return n, nil
+}
@@ -0,0 +1,99 @@
+package serve
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOnChange(t *testing.T) {
+	runs := func(dir string) int {
+		b, _ := os.ReadFile(filepath.Join(dir, "runs.log"))
+		return strings.Count(string(b), "\n")
+	}
+	for _, test := range []struct {
+		name    string
+		config  Config
+		changes []string
+		runs    int
+	}{
+		//the output of a catch-all command is found by
+		//a later scan, and doesn't run it again
+		{"poll", Config{
+			Watcher:         watchPoll,
+			PollInterval:    50 * time.Millisecond,
+			LiveReloadDelay: 10 * time.Millisecond,
+			OnChange:        []string{"echo run >> runs.log"},
+		}, []string{"a.txt"}, 1},
+		//a burst of changes runs each matching command once
+		{"burst", Config{
+			Watcher:         watchNotify,
+			LiveReloadDelay: 100 * time.Millisecond,
+			OnChange:        []string{"*.scss=echo run >> runs.log", "*.go=echo go >> go.log"},
+		}, []string{"a.scss", "b.scss", "c.scss", "d.txt"}, 1},
+	} {
+		dir := t.TempDir()
+		c := test.config
+		c.Directory, c.Quiet, c.LiveReload = dir, true, true
+		h, err := NewHandler(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		events := lrListen(t, h)
+		lrWatching(t, h, dir)
+		for _, p := range test.changes {
+			os.WriteFile(filepath.Join(dir, p), []byte(p), 0644)
+		}
+		if e := lrExpect(t, events); e.event != "change" {
+			t.Fatalf("%s: unexpected event: %+v", test.name, e)
+		}
+		time.Sleep(500 * time.Millisecond)
+		h.Close()
+		if got := runs(dir); got != test.runs {
+			t.Errorf("%s: ran %d times, want %d", test.name, got, test.runs)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "go.log")); err == nil {
+			t.Errorf("%s: unmatched command ran", test.name)
+		}
+	}
+}
+
+func TestOnChangeFailure(t *testing.T) {
+	dir := t.TempDir()
+	h, err := NewHandler(Config{
+		Directory:  dir,
+		Quiet:      true,
+		LiveReload: true,
+		Watcher:    watchNotify,
+		OnChange:   []string{"echo broken; exit 3"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	events := lrListen(t, h)
+	lrWatching(t, h, dir)
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644)
+	e := lrExpect(t, events)
+	if e.event != "failure" || !strings.Contains(e.data, "exit status 3") || !strings.Contains(e.data, "broken") {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+	//no reload follows the failure
+	select {
+	case e := <-events:
+		t.Fatalf("unexpected event: %+v", e)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	b := &tailBuffer{max: 4}
+	for _, w := range []string{"ab", "cd", "ef", "0123456789"} {
+		b.Write([]byte(w))
+	}
+	if string(b.b) != "6789" {
+		t.Errorf("got %q", b.b)
+	}
+}
@@ -0,0 +1,93 @@
+package serve
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"sort"
+	"strings"
+)
+
+//proxyRule sends requests within prefix to an upstream
+type proxyRule struct {
+	prefix string
+	strip  bool
+	set    http.Header
+	del    []string
+	proxy  *httputil.ReverseProxy
+}
+
+//parseProxyRules parses 'prefix=url [+Header:value] [-Header]' rules,
+//when the url has a path (see parseUpstream), it replaces the prefix (like nginx's
+//proxy_pass), rules are sorted longest prefix first
+func parseProxyRules(rules []string) ([]*proxyRule, error) {
+	parsed := []*proxyRule{}
+	for _, rule := range rules {
+		kv := strings.SplitN(rule, "=", 2)
+		if len(kv) != 2 || !strings.HasPrefix(kv[0], "/") {
+			return nil, fmt.Errorf("Invalid proxy rule: %s (must be in the form '/prefix=url')", rule)
+		}
+		fields := strings.Fields(kv[1])
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("Invalid proxy rule: %s (missing url)", rule)
+		}
+		up, err := parseUpstream(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		p := &proxyRule{
+			prefix: kv[0],
+			strip:  up.url.Path != "",
+			set:    http.Header{},
+		}
+		for _, f := range fields[1:] {
+			switch {
+			case strings.HasPrefix(f, "+") && strings.Contains(f, ":"):
+				hv := strings.SplitN(f[1:], ":", 2)
+				p.set.Add(hv[0], hv[1])
+			case strings.HasPrefix(f, "-") && len(f) > 1:
+				p.del = append(p.del, f[1:])
+			default:
+				return nil, fmt.Errorf("Invalid proxy header: %s (must be in the form '+Name:value' or '-Name')", f)
+			}
+		}
+		p.proxy = newProxy(up)
+		director := p.proxy.Director
+		p.proxy.Director = func(r *http.Request) {
+			if p.strip {
+				r.URL.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, strings.TrimSuffix(p.prefix, "/")), "/")
+				r.URL.RawPath = ""
+			}
+			director(r)
+			for _, h := range p.del {
+				r.Header.Del(h)
+			}
+			for h, vs := range p.set {
+				r.Header[h] = vs
+			}
+		}
+		parsed = append(parsed, p)
+	}
+	sort.SliceStable(parsed, func(i, j int) bool {
+		return len(parsed[i].prefix) > len(parsed[j].prefix)
+	})
+	return parsed, nil
+}
+
+//match reports whether p is the prefix, or within it
+func (p *proxyRule) match(path string) bool {
+	prefix := strings.TrimSuffix(p.prefix, "/")
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+//proxied sends r to the upstream of the longest matching rule,
+//reporting whether there was one
+func (s *Handler) proxied(w http.ResponseWriter, r *http.Request) bool {
+	for _, p := range s.proxies {
+		if p.match(r.URL.Path) {
+			p.proxy.ServeHTTP(w, r)
+			return true
+		}
+	}
+	return false
+}
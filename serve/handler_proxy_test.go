@@ -0,0 +1,467 @@
+package serve
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProxyRules(t *testing.T) {
+	upstream := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, name+" "+r.URL.Path+" "+r.Header.Get("X-Key")+r.Header.Get("Cookie"))
+		}))
+	}
+	api, auth := upstream("api"), upstream("auth")
+	defer api.Close()
+	defer auth.Close()
+	h, err := NewHandler(Config{
+		Directory: t.TempDir(),
+		Quiet:     true,
+		Proxy: []string{
+			"/api=" + api.URL + " +X-Key:k -Cookie",
+			"/api/auth=" + auth.URL + "/v1/",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	for path, want := range map[string]string{
+		"/api/users":      "api /api/users k",
+		"/api":            "api /api k",
+		"/api/auth/login": "auth /v1/login c",
+		"/apix":           "Not found",
+	} {
+		r := httptest.NewRequest("GET", path, nil)
+		r.Header.Set("Cookie", "c")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if got := w.Body.String(); got != want {
+			t.Errorf("%s: got %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestFallbackStreaming(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") == "echo" {
+			if r.Header.Get("Origin") != "http://"+r.Host {
+				w.WriteHeader(403)
+				return
+			}
+			conn, rw, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nAuthorization: Basic dTpw\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+			rw.Flush()
+			line, _ := rw.ReadString('\n')
+			rw.WriteString(line)
+			rw.Flush()
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: 1\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer upstream.Close()
+	h, err := NewHandler(Config{Directory: t.TempDir(), Fallback: []string{upstream.URL}, Auth: "u:p"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	server := httptest.NewServer(h)
+	defer server.Close()
+	//server-sent events arrive before the response ends
+	resp, err := http.Get(strings.Replace(server.URL, "://", "://u:p@", 1) + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	resp.Body.Close()
+	if err != nil || line != "data: 1\n" {
+		t.Fatalf("event: %q %v", line, err)
+	}
+	//upgraded connections are relayed, with the origin swapped
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	host := server.Listener.Addr().String()
+	io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: "+host+"\r\nOrigin: http://"+host+"\r\nAuthorization: Basic dTpw\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err = http.ReadResponse(br, nil)
+	if err != nil || resp.StatusCode != 101 {
+		t.Fatalf("upgrade: %v %v", resp, err)
+	}
+	io.WriteString(conn, "ping\n")
+	if line, err := br.ReadString('\n'); err != nil || line != "ping\n" {
+		t.Fatalf("echo: %q %v", line, err)
+	}
+}
+
+func TestUnixUpstream(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "app.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skip(err)
+	}
+	upstream := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Host+" "+r.URL.Path)
+	})}
+	go upstream.Serve(l)
+	defer upstream.Close()
+	h, err := NewHandler(Config{
+		Directory: t.TempDir(),
+		Quiet:     true,
+		Fallback:  []string{"unix://" + sock},
+		Proxy:     []string{"/api=unix://" + sock + ":/v1/"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	for path, want := range map[string]string{
+		"/missing":   "example.com /missing",
+		"/api/users": "example.com /v1/users",
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "http://example.com"+path, nil))
+		if got := w.Body.String(); got != want {
+			t.Errorf("%s: got %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestFallbackBalance(t *testing.T) {
+	upstream := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, name)
+		}))
+	}
+	a, b, down := upstream("a"), upstream("b"), upstream("down")
+	defer a.Close()
+	defer b.Close()
+	down.Close()
+	h, err := NewHandler(Config{
+		Directory:       t.TempDir(),
+		Quiet:           true,
+		Fallback:        []string{a.URL, down.URL, b.URL},
+		FallbackRetries: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	get := func(method string) (int, string) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, "/missing", strings.NewReader("")))
+		return w.Code, w.Body.String()
+	}
+	counts := map[string]int{}
+	for i := 0; i < 6; i++ {
+		code, body := get("GET")
+		if code != 200 {
+			t.Fatalf("GET: %d %s", code, body)
+		}
+		counts[body]++
+	}
+	if counts["a"] != 3 || counts["b"] != 3 {
+		t.Fatalf("expected even spread, got %v", counts)
+	}
+	//the failed upstream is ejected, so posts are not sent to it
+	for i := 0; i < 3; i++ {
+		if code, body := get("POST"); code != 200 {
+			t.Fatalf("POST: %d %s", code, body)
+		}
+	}
+}
+
+func TestFallbackTriggers(t *testing.T) {
+	gone := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			gone++
+			b, _ := io.ReadAll(r.Body)
+			w.WriteHeader(404)
+			io.WriteString(w, "gone "+string(b))
+			return
+		}
+		if r.Method == "GET" && r.URL.Path == "/file.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, "upstream")
+	}))
+	defer upstream.Close()
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	os.WriteFile(filepath.Join(dir, "file.txt"), []byte("file"), 0644)
+	for _, test := range []struct {
+		config       Config
+		method, path string
+		want         string
+	}{
+		{Config{FallbackOn: []string{"missing"}}, "GET", "/sub/", "dir"},
+		{Config{FallbackOn: []string{"missing"}}, "GET", "/nope", "upstream"},
+		{Config{FallbackOn: []string{"method:!GET"}}, "POST", "/file.txt", "upstream"},
+		{Config{FallbackOn: []string{"method:!GET"}}, "GET", "/file.txt", "file"},
+		{Config{FallbackOn: []string{"/api/*"}}, "GET", "/api/v1/x", "upstream"},
+		{Config{FallbackOn: []string{"status:403"}, NoList: true}, "GET", "/sub/", "upstream"},
+		{Config{FallbackFirst: true}, "GET", "/file.txt", "file"},
+		{Config{FallbackFirst: true}, "GET", "/other", "upstream"},
+		{Config{FallbackOn: []string{"missing", "status:404"}}, "POST", "/gone", "gone body"},
+	} {
+		c := test.config
+		c.Directory, c.Quiet, c.Fallback = dir, true, []string{upstream.URL}
+		h, err := NewHandler(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest(test.method, test.path, strings.NewReader("body"))
+		r.Header.Set("Accept", "text/plain")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		h.Close()
+		got := w.Body.String()
+		if test.want == "dir" {
+			if w.Code != 200 || strings.Contains(got, "upstream") {
+				t.Errorf("%v %s %s: expected a listing, got %d %q", c.FallbackOn, test.method, test.path, w.Code, got)
+			}
+		} else if got != test.want {
+			t.Errorf("%v %s %s: got %q, want %q", c.FallbackOn, test.method, test.path, got, test.want)
+		}
+	}
+	if gone != 1 {
+		t.Errorf("the fallback was sent %d requests, want 1", gone)
+	}
+}
+
+func TestFallbackRecordReplay(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Upstream", "1")
+		w.WriteHeader(201)
+		io.WriteString(w, r.Method+" "+r.URL.RequestURI()+" "+string(b))
+	}))
+	recordings := t.TempDir()
+	do := func(h http.Handler, method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, url, strings.NewReader(body)))
+		return w
+	}
+	h, err := NewHandler(Config{Directory: t.TempDir(), Quiet: true, Fallback: []string{upstream.URL}, FallbackRecord: recordings})
+	if err != nil {
+		t.Fatal(err)
+	}
+	do(h, "GET", "/api/users?b=2&a=1", "")
+	do(h, "POST", "/api/users", "one")
+	h.Close()
+	upstream.Close()
+	for _, test := range []struct {
+		match, method, url, body string
+		code                     int
+		want                     string
+	}{
+		{"", "GET", "/api/users?a=1&b=2", "", 201, "GET /api/users?b=2&a=1 "},
+		{"", "GET", "/api/users", "", 404, ""},
+		{matchPath, "GET", "/api/users", "", 201, "GET /api/users?b=2&a=1 "},
+		{matchBody, "POST", "/api/users", "one", 201, "POST /api/users one"},
+		{matchBody, "POST", "/api/users", "two", 404, ""},
+	} {
+		h, err := NewHandler(Config{Directory: t.TempDir(), Quiet: true, FallbackReplay: recordings, FallbackMatch: test.match})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := do(h, test.method, test.url, test.body)
+		h.Close()
+		if w.Code != test.code || (test.want != "" && w.Body.String() != test.want) {
+			t.Errorf("%s %s %s: got %d %q", test.match, test.method, test.url, w.Code, w.Body)
+		}
+		if test.code == 201 && w.Header().Get("X-Upstream") != "1" {
+			t.Errorf("%s %s %s: missing recorded header", test.match, test.method, test.url)
+		}
+	}
+}
+
+func TestFallbackRecordLargeBody(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := io.Copy(io.Discard, r.Body)
+		io.WriteString(w, strconv.FormatInt(n, 10))
+	}))
+	defer upstream.Close()
+	recordings := t.TempDir()
+	large := strings.Repeat("a", recordMaxBody+1)
+	for _, config := range []Config{
+		{Fallback: []string{upstream.URL}, FallbackRecord: recordings},
+		{Fallback: []string{upstream.URL}, FallbackReplay: recordings, FallbackMatch: matchBody},
+	} {
+		config.Directory, config.Quiet = t.TempDir(), true
+		h, err := NewHandler(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, chunked := range []bool{false, true} {
+			for _, body := range []string{"small", large} {
+				r := httptest.NewRequest("POST", "/api/upload", strings.NewReader(body))
+				if chunked {
+					r.ContentLength = -1
+				}
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+				//large bodies are still proxied, and aren't replayed
+				if w.Code != 200 || w.Body.String() != strconv.Itoa(len(body)) {
+					t.Errorf("%d bytes (chunked %v): got %d %q", len(body), chunked, w.Code, w.Body)
+				}
+				if replayed := w.Header().Get("Serve-Replay") != ""; replayed != (config.FallbackReplay != "" && body == "small") {
+					t.Errorf("%d bytes (chunked %v): replayed %v", len(body), chunked, replayed)
+				}
+			}
+		}
+		h.Close()
+		//only the small body is recorded
+		if files, _ := filepath.Glob(filepath.Join(recordings, "*.json")); len(files) != 1 {
+			t.Fatalf("got %d recordings, want 1", len(files))
+		}
+	}
+}
+
+func TestFallbackCache(t *testing.T) {
+	hits := 0
+	down := false
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down {
+			w.WriteHeader(503)
+			return
+		}
+		hits++
+		switch r.URL.Path {
+		case "/fresh":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "Accept")
+		case "/expired":
+			w.Header().Set("Cache-Control", "max-age=1, stale-if-error=60")
+			w.Header().Set("Age", "1")
+		case "/private":
+			w.Header().Set("Cache-Control", "private, max-age=60")
+		}
+		io.WriteString(w, r.URL.Path+" "+r.Header.Get("Accept"))
+	}))
+	defer upstream.Close()
+	for _, dir := range []string{"", t.TempDir()} {
+		hits = 0
+		down = false
+		h, err := NewHandler(Config{Directory: t.TempDir(), Quiet: true, Fallback: []string{upstream.URL}, FallbackCache: 1 << 20, FallbackCacheDir: dir})
+		if err != nil {
+			t.Fatal(err)
+		}
+		do := func(url, accept, status, body string) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", url, nil)
+			r.Header.Set("Accept", accept)
+			h.ServeHTTP(w, r)
+			if got := w.Header().Get(cacheHeader); got != status || (body != "" && w.Body.String() != body) {
+				t.Errorf("%q %s %s: got %s %d %q", dir, url, accept, got, w.Code, w.Body)
+			}
+		}
+		do("/fresh", "a", "MISS", "/fresh a")
+		do("/fresh", "a", "HIT", "/fresh a")
+		do("/fresh", "b", "MISS", "/fresh b")
+		do("/fresh", "b", "HIT", "/fresh b")
+		do("/private", "", "MISS", "")
+		do("/private", "", "MISS", "")
+		do("/expired", "", "MISS", "")
+		if hits != 5 {
+			t.Errorf("%q: got %d upstream requests, want 5", dir, hits)
+		}
+		down = true
+		do("/expired", "", "STALE", "/expired ")
+		do("/private", "", "MISS", "")
+		h.Close()
+	}
+}
+
+func TestFallbackCacheOuterHeaders(t *testing.T) {
+	down := false
+	c, err := newResponseCache(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down {
+			w.Header().Set("X-Error", "1")
+			w.WriteHeader(503)
+			return
+		}
+		w.Header().Set("X-Upstream", "1")
+		if r.URL.Path == "/fresh" {
+			w.Header().Set("Cache-Control", "max-age=60")
+		} else {
+			w.Header().Set("Cache-Control", "max-age=1, stale-if-error=60")
+			w.Header().Set("Age", "1")
+		}
+		io.WriteString(w, r.URL.Path)
+	}), 1<<20, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	do := func(url, status string) {
+		t.Helper()
+		//an outer layer, whose headers are kept (and never stored)
+		n++
+		outer := strconv.Itoa(n)
+		w := httptest.NewRecorder()
+		w.Header().Set("X-Outer", outer)
+		c.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		h := w.Header()
+		if h.Get(cacheHeader) != status || h.Get("X-Outer") != outer || h.Get("X-Upstream") != "1" || h.Get("X-Error") != "" {
+			t.Errorf("%s: got %d %v", url, w.Code, h)
+		}
+	}
+	do("/fresh", "MISS")
+	do("/fresh", "HIT")
+	do("/expired", "MISS")
+	down = true
+	do("/expired", "STALE")
+}
+
+func TestFallbackRecordReplayLive(t *testing.T) {
+	up := true
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up {
+			w.WriteHeader(503)
+			return
+		}
+		io.WriteString(w, "live")
+	}))
+	defer upstream.Close()
+	recordings := t.TempDir()
+	//recordings made behind the cache are replayed as they are saved
+	h, err := NewHandler(Config{Directory: t.TempDir(), Quiet: true, Fallback: []string{upstream.URL}, FallbackCache: 1 << 20, FallbackRecord: recordings, FallbackReplay: recordings})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api", nil))
+	up = false
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/api", nil))
+	if w.Body.String() != "live" || w.Header().Get("Serve-Replay") == "" {
+		t.Errorf("expected a replay, got %d %q", w.Code, w.Body)
+	}
+	//recordings without a status are rejected
+	os.WriteFile(filepath.Join(recordings, "bad.json"), []byte(`{"method":"GET","path":"/bad"}`), 0644)
+	if _, err := NewHandler(Config{Directory: t.TempDir(), FallbackReplay: recordings}); err == nil {
+		t.Errorf("expected an invalid recording error")
+	}
+}
@@ -0,0 +1,334 @@
+package serve
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+//replay match strictness
+const (
+	matchPath  = "path"
+	matchQuery = "query"
+	matchBody  = "body"
+)
+
+//recordMaxBody is the largest response, and
+//request body, of the exchanges recorded
+const recordMaxBody = 32 << 20
+
+//recording is a proxied request and its response,
+//each is stored in its own json file
+type recording struct {
+	Method   string      `json:"method"`
+	Path     string      `json:"path"`
+	Query    string      `json:"query,omitempty"`
+	BodyHash string      `json:"bodySha256,omitempty"`
+	Time     time.Time   `json:"time"`
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header"`
+	Body     string      `json:"body,omitempty"`
+	Encoding string      `json:"encoding,omitempty"`
+}
+
+//key identifies the request of rec, at a match strictness
+func (rec *recording) key(match string) string {
+	k := rec.Method + " " + rec.Path
+	if match == matchQuery || match == matchBody {
+		k += "?" + rec.Query
+	}
+	if match == matchBody {
+		k += " " + rec.BodyHash
+	}
+	return k
+}
+
+//requestRecording describes r, without its body
+func requestRecording(r *http.Request) *recording {
+	return &recording{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.Query().Encode(),
+	}
+}
+
+//hashReader hashes a request body as it is read
+type hashReader struct {
+	io.ReadCloser
+	mut sync.Mutex
+	h   hash.Hash
+	n   int64
+	eof bool
+}
+
+func (hr *hashReader) Read(p []byte) (int, error) {
+	n, err := hr.ReadCloser.Read(p)
+	hr.mut.Lock()
+	hr.h.Write(p[:n])
+	hr.n += int64(n)
+	if err == io.EOF {
+		hr.eof = true
+	}
+	hr.mut.Unlock()
+	return n, err
+}
+
+//sum returns the hash of the body, false when the body wasn't
+//read to the end (it may still be sent), or is too large
+func (hr *hashReader) sum() (string, bool) {
+	hr.mut.Lock()
+	defer hr.mut.Unlock()
+	if !hr.eof || hr.n > recordMaxBody {
+		return "", false
+	}
+	if hr.n == 0 {
+		return "", true
+	}
+	return hex.EncodeToString(hr.h.Sum(nil)), true
+}
+
+//unsafeName matches characters replaced in recording file names
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+//filename is a readable, unique name for the recording
+func (rec *recording) filename() string {
+	name := unsafeName.ReplaceAllString(rec.Path, "_")
+	if len(name) > 80 {
+		name = name[:80]
+	}
+	sum := sha256.Sum256([]byte(rec.key(matchBody)))
+	return rec.Method + name + "_" + hex.EncodeToString(sum[:4]) + ".json"
+}
+
+//recorder saves each request proxied by next, and its response
+type recorder struct {
+	dir    string
+	next   http.Handler
+	quiet  bool
+	onSave func(*recording)
+}
+
+func (rr *recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec := requestRecording(r)
+	//the body is hashed as it's sent
+	var body *hashReader
+	if r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0 {
+		body = &hashReader{ReadCloser: r.Body, h: sha256.New()}
+		r.Body = body
+	}
+	rw := &recordWriter{ResponseWriter: w}
+	rr.next.ServeHTTP(rw, r)
+	//skip upgraded, oversized and unreachable responses
+	if rw.skip || rw.status == http.StatusBadGateway {
+		return
+	}
+	//and requests with oversized (or unsent) bodies
+	if body != nil {
+		sum, ok := body.sum()
+		if !ok {
+			return
+		}
+		rec.BodyHash = sum
+	}
+	rec.Time = time.Now()
+	rec.Status = rw.status
+	if rec.Status == 0 {
+		rec.Status = 200
+	}
+	rec.Header = w.Header().Clone()
+	if b := rw.body.Bytes(); utf8.Valid(b) {
+		rec.Body = string(b)
+	} else {
+		rec.Body = base64.StdEncoding.EncodeToString(b)
+		rec.Encoding = "base64"
+	}
+	if err := rr.save(rec); err != nil {
+		if !rr.quiet {
+			fmt.Printf("Failed to save recording: %s\n", err)
+		}
+		return
+	}
+	if rr.onSave != nil {
+		rr.onSave(rec)
+	}
+}
+
+//save writes rec into the recordings directory
+func (rr *recorder) save(rec *recording) error {
+	b, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(rr.dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(rr.dir, ".recording-*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(rr.dir, rec.filename()))
+}
+
+//recordWriter copies a response as it is written
+type recordWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+	skip   bool
+}
+
+func (rw *recordWriter) WriteHeader(code int) {
+	if rw.status == 0 {
+		rw.status = code
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = 200
+	}
+	if !rw.skip {
+		if rw.body.Len()+len(b) > recordMaxBody {
+			rw.skip = true
+			rw.body = bytes.Buffer{}
+		} else {
+			rw.body.Write(b)
+		}
+	}
+	return rw.ResponseWriter.Write(b)
+}
+
+func (rw *recordWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (rw *recordWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	rw.skip = true
+	hj, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("Hijack not supported")
+	}
+	return hj.Hijack()
+}
+
+//replayer responds with recordings, requests without one
+//are sent to next, or are not found when there is none
+type replayer struct {
+	match string
+	next  http.Handler
+	mut   sync.Mutex
+	index map[string]*recording
+}
+
+//newReplayer loads the recordings within dir,
+//the latest recording of each request is replayed
+func newReplayer(dir, match string, next http.Handler) (*replayer, error) {
+	rp := &replayer{match: match, next: next, index: map[string]*recording{}}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		rec := &recording{}
+		if err := json.Unmarshal(b, rec); err != nil {
+			return nil, fmt.Errorf("Invalid recording %s: %s", f, err)
+		}
+		if rec.Status < 100 || rec.Status > 999 {
+			return nil, fmt.Errorf("Invalid recording %s: status %d", f, rec.Status)
+		}
+		rp.add(rec)
+	}
+	return rp, nil
+}
+
+//add indexes rec, unless a later recording of its request exists
+func (rp *replayer) add(rec *recording) {
+	k := rec.key(rp.match)
+	rp.mut.Lock()
+	if prev, ok := rp.index[k]; !ok || !prev.Time.After(rec.Time) {
+		rp.index[k] = rec
+	}
+	rp.mut.Unlock()
+}
+
+func (rp *replayer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req := requestRecording(r)
+	lookup := true
+	if rp.match == matchBody {
+		//bodies too large to record are never replayed
+		b, ok, err := bufferBody(r, recordMaxBody)
+		if err != nil {
+			w.WriteHeader(400)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		lookup = ok
+		if len(b) > 0 {
+			sum := sha256.Sum256(b)
+			req.BodyHash = hex.EncodeToString(sum[:])
+		}
+	}
+	var rec *recording
+	if lookup {
+		rp.mut.Lock()
+		rec = rp.index[req.key(rp.match)]
+		rp.mut.Unlock()
+	}
+	if rec == nil {
+		if rp.next != nil {
+			rp.next.ServeHTTP(w, r)
+			return
+		}
+		w.WriteHeader(404)
+		w.Write([]byte("No recording of " + r.Method + " " + r.URL.RequestURI()))
+		return
+	}
+	body := []byte(rec.Body)
+	if rec.Encoding == "base64" {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(rec.Body); err != nil {
+			w.WriteHeader(500)
+			w.Write([]byte(err.Error()))
+			return
+		}
+	}
+	h := w.Header()
+	for k, v := range rec.Header {
+		h[k] = v
+	}
+	h.Set("Content-Length", strconv.Itoa(len(body)))
+	h.Del("Transfer-Encoding")
+	h.Set("Serve-Replay", rec.Time.Format(time.RFC3339))
+	w.WriteHeader(rec.Status)
+	w.Write(body)
+}
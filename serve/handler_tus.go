@@ -0,0 +1,319 @@
+package serve
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//tus 1.0 resumable uploads (https://tus.io/protocols/resumable-upload)
+
+//tusPath is the upload creation endpoint, individual
+//uploads are located at tusPath + <id>
+const tusPath = "/__serve/uploads/"
+
+const tusVersion = "1.0.0"
+
+//tusUpload is stored alongside each partial upload
+type tusUpload struct {
+	ID       string    `json:"-"`
+	Length   int64     `json:"length"`
+	Metadata string    `json:"metadata"`
+	Target   string    `json:"target"`
+	Expires  time.Time `json:"expires"`
+}
+
+func (s *Handler) tusFile(id, ext string) string {
+	return filepath.Join(s.uploadDir, id+ext)
+}
+
+//tus handles all requests to the tus endpoint
+func (s *Handler) tus(w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	h.Set("Tus-Resumable", tusVersion)
+	h.Set("Cache-Control", "no-store")
+	//shorthand
+	reply := func(c int, msg string) {
+		w.WriteHeader(c)
+		if msg != "" {
+			w.Write([]byte(msg))
+		}
+	}
+	if r.Method == "OPTIONS" {
+		h.Set("Tus-Version", tusVersion)
+		h.Set("Tus-Extension", "creation,termination,expiration")
+		reply(204, "")
+		return
+	}
+	if r.Header.Get("Tus-Resumable") != tusVersion {
+		h.Set("Tus-Version", tusVersion)
+		reply(412, "Unsupported tus version")
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, tusPath)
+	if id == "" {
+		if r.Method != "POST" {
+			reply(405, "Method not allowed")
+			return
+		}
+		s.tusCreate(w, r)
+		return
+	}
+	if len(id) != 32 || strings.Trim(id, "0123456789abcdef") != "" {
+		reply(404, "Not found")
+		return
+	}
+	//one request per upload at a time
+	s.uploadsMut.Lock()
+	if s.uploading[id] {
+		s.uploadsMut.Unlock()
+		reply(423, "Upload is locked by another request")
+		return
+	}
+	s.uploading[id] = true
+	s.uploadsMut.Unlock()
+	defer func() {
+		s.uploadsMut.Lock()
+		delete(s.uploading, id)
+		s.uploadsMut.Unlock()
+	}()
+	u, err := s.tusLoad(id)
+	if err != nil {
+		reply(404, "Not found")
+		return
+	}
+	if time.Now().After(u.Expires) {
+		s.tusRemove(id)
+		reply(410, "Upload expired")
+		return
+	}
+	switch r.Method {
+	case "HEAD":
+		info, err := os.Stat(s.tusFile(id, ".part"))
+		if err != nil {
+			reply(404, "")
+			return
+		}
+		h.Set("Upload-Offset", strconv.FormatInt(info.Size(), 10))
+		h.Set("Upload-Length", strconv.FormatInt(u.Length, 10))
+		if u.Metadata != "" {
+			h.Set("Upload-Metadata", u.Metadata)
+		}
+		reply(200, "")
+	case "PATCH":
+		s.tusPatch(w, r, u)
+	case "DELETE":
+		s.tusRemove(id)
+		reply(204, "")
+	default:
+		reply(405, "Method not allowed")
+	}
+}
+
+//tusCreate implements the creation extension
+func (s *Handler) tusCreate(w http.ResponseWriter, r *http.Request) {
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		w.WriteHeader(400)
+		w.Write([]byte("Invalid Upload-Length"))
+		return
+	}
+	meta := tusMetadata(r.Header.Get("Upload-Metadata"))
+	target, code, msg := s.uploadTarget(meta["dir"], meta["filename"], length)
+	if code != 0 {
+		w.WriteHeader(code)
+		w.Write([]byte(msg))
+		return
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		w.WriteHeader(500)
+		return
+	}
+	u := &tusUpload{
+		ID:       hex.EncodeToString(b),
+		Length:   length,
+		Metadata: r.Header.Get("Upload-Metadata"),
+		Target:   target,
+		Expires:  time.Now().Add(s.c.UploadExpiry),
+	}
+	if err := os.MkdirAll(s.uploadDir, 0755); err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	if err := os.WriteFile(s.tusFile(u.ID, ".part"), nil, 0644); err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	if err := s.tusSave(u); err != nil {
+		s.tusRemove(u.ID)
+		w.WriteHeader(500)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	//empty uploads are complete, no PATCH will follow
+	if u.Length == 0 {
+		if err := s.tusFinish(u); os.IsExist(err) {
+			w.WriteHeader(409)
+			w.Write([]byte("File already exists"))
+			return
+		} else if _, ok := err.(quotaError); ok {
+			w.WriteHeader(413)
+			w.Write([]byte(err.Error()))
+			return
+		} else if err != nil {
+			w.WriteHeader(500)
+			w.Write([]byte(err.Error()))
+			return
+		}
+	}
+	w.Header().Set("Location", tusPath+u.ID)
+	w.Header().Set("Upload-Expires", u.Expires.UTC().Format(http.TimeFormat))
+	w.WriteHeader(201)
+}
+
+//tusPatch appends the request body to the upload
+func (s *Handler) tusPatch(w http.ResponseWriter, r *http.Request, u *tusUpload) {
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		w.WriteHeader(415)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		w.WriteHeader(400)
+		w.Write([]byte("Invalid Upload-Offset"))
+		return
+	}
+	f, err := os.OpenFile(s.tusFile(u.ID, ".part"), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	info, err := f.Stat()
+	if err != nil || info.Size() != offset {
+		f.Close()
+		w.WriteHeader(409)
+		w.Write([]byte("Upload-Offset mismatch"))
+		return
+	}
+	//anything written before a failure is kept,
+	//the client resumes from the new offset
+	n, err := io.Copy(f, io.LimitReader(r.Body, u.Length-offset))
+	f.Close()
+	offset += n
+	u.Expires = time.Now().Add(s.c.UploadExpiry)
+	s.tusSave(u)
+	w.Header().Set("Upload-Expires", u.Expires.UTC().Format(http.TimeFormat))
+	w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	if err != nil {
+		w.WriteHeader(500)
+		return
+	}
+	if offset == u.Length {
+		if err := s.tusFinish(u); os.IsExist(err) {
+			w.WriteHeader(409)
+			w.Write([]byte("File already exists"))
+			return
+		} else if _, ok := err.(quotaError); ok {
+			w.WriteHeader(413)
+			w.Write([]byte(err.Error()))
+			return
+		} else if err != nil {
+			w.WriteHeader(500)
+			w.Write([]byte(err.Error()))
+			return
+		}
+	}
+	w.WriteHeader(204)
+}
+
+//tusFinish moves a completed upload to its target
+func (s *Handler) tusFinish(u *tusUpload) error {
+	if _, err := s.place(s.tusFile(u.ID, ".part"), u.Target); err != nil {
+		s.tusRemove(u.ID)
+		return err
+	}
+	return os.Remove(s.tusFile(u.ID, ".info"))
+}
+
+func (s *Handler) tusLoad(id string) (*tusUpload, error) {
+	b, err := os.ReadFile(s.tusFile(id, ".info"))
+	if err != nil {
+		return nil, err
+	}
+	u := &tusUpload{ID: id}
+	if err := json.Unmarshal(b, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (s *Handler) tusSave(u *tusUpload) error {
+	b, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.tusFile(u.ID, ".info"), b, 0644)
+}
+
+func (s *Handler) tusRemove(id string) {
+	os.Remove(s.tusFile(id, ".part"))
+	os.Remove(s.tusFile(id, ".info"))
+}
+
+//tusExpire removes all stale partial uploads
+func (s *Handler) tusExpire() {
+	names, err := filepath.Glob(filepath.Join(s.uploadDir, "*.info"))
+	if err != nil {
+		return
+	}
+	for _, n := range names {
+		id := strings.TrimSuffix(filepath.Base(n), ".info")
+		if u, err := s.tusLoad(id); err == nil && time.Now().Before(u.Expires) {
+			continue
+		}
+		s.uploadsMut.Lock()
+		if !s.uploading[id] {
+			s.tusRemove(id)
+		}
+		s.uploadsMut.Unlock()
+	}
+}
+
+//tusMetadata parses an Upload-Metadata header
+func tusMetadata(header string) map[string]string {
+	m := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), " ", 2)
+		if kv[0] == "" {
+			continue
+		}
+		v := ""
+		if len(kv) == 2 {
+			if b, err := base64.StdEncoding.DecodeString(kv[1]); err == nil {
+				v = string(b)
+			}
+		}
+		m[kv[0]] = v
+	}
+	return m
+}
+
+//hidden reports whether rel is within the upload
+//directory, which is never served or listed
+func (s *Handler) hidden(rel string) bool {
+	if s.uploadRel == "" {
+		return false
+	}
+	return rel == s.uploadRel || strings.HasPrefix(rel, s.uploadRel+"/")
+}
@@ -0,0 +1,64 @@
+package serve
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTusUpload(t *testing.T) {
+	dir := t.TempDir()
+	h, err := NewHandler(Config{Directory: dir, Auth: "u:p", Write: true, Upload: true, Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	do := func(method, url string, body string, headers ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, url, strings.NewReader(body))
+		r.SetBasicAuth("u", "p")
+		r.Header.Set("Tus-Resumable", tusVersion)
+		for i := 0; i < len(headers); i += 2 {
+			r.Header.Set(headers[i], headers[i+1])
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+	meta := "filename " + base64.StdEncoding.EncodeToString([]byte("f.txt"))
+	w := do("POST", tusPath, "", "Upload-Length", "10", "Upload-Metadata", meta)
+	if w.Code != 201 {
+		t.Fatalf("create: %d %s", w.Code, w.Body)
+	}
+	loc := w.Header().Get("Location")
+	octet := "application/offset+octet-stream"
+	if w = do("PATCH", loc, "01234", "Upload-Offset", "0", "Content-Type", octet); w.Code != 204 {
+		t.Fatalf("patch: %d %s", w.Code, w.Body)
+	}
+	if w = do("PATCH", loc, "56789", "Upload-Offset", "0", "Content-Type", octet); w.Code != 409 {
+		t.Fatalf("stale offset: expected 409, got %d", w.Code)
+	}
+	if w = do("HEAD", loc, ""); w.Header().Get("Upload-Offset") != "5" {
+		t.Fatalf("head: offset %q", w.Header().Get("Upload-Offset"))
+	}
+	if w = do("PATCH", loc, "56789", "Upload-Offset", "5", "Content-Type", octet); w.Code != 204 {
+		t.Fatalf("patch: %d %s", w.Code, w.Body)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "f.txt"))
+	if err != nil || string(b) != "0123456789" {
+		t.Fatalf("upload contents: %q %v", b, err)
+	}
+	if w = do("HEAD", loc, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("completed upload should be gone, got %d", w.Code)
+	}
+	empty := "filename " + base64.StdEncoding.EncodeToString([]byte("empty.txt"))
+	if w = do("POST", tusPath, "", "Upload-Length", "0", "Upload-Metadata", empty); w.Code != 201 {
+		t.Fatalf("create empty: %d %s", w.Code, w.Body)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "empty.txt")); err != nil || info.Size() != 0 {
+		t.Fatalf("empty upload should be complete: %v", err)
+	}
+}
@@ -0,0 +1,338 @@
+package serve
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//versionsDir is the hidden directory within the root which
+//holds previous versions of overwritten uploads
+const versionsDir = ".versions"
+
+//overwrite policies
+const (
+	overwriteReject  = "reject"
+	overwriteReplace = "overwrite"
+	overwriteRename  = "rename"
+	overwriteVersion = "version"
+)
+
+//put uploads the request body in a single request, it is
+//buffered in the upload directory then moved into place
+func (s *Handler) put(w http.ResponseWriter, r *http.Request) {
+	rel := s.relpath(r.URL.Path)
+	if (s.c.UploadQuota > 0 || s.c.UploadDirQuota > 0) && r.ContentLength < 0 {
+		w.WriteHeader(411)
+		w.Write([]byte("Content-Length is required when quotas are enabled"))
+		return
+	}
+	target, code, msg := s.uploadTarget(path.Dir("/"+rel), path.Base(rel), r.ContentLength)
+	if code != 0 {
+		w.WriteHeader(code)
+		w.Write([]byte(msg))
+		return
+	}
+	if err := os.MkdirAll(s.uploadDir, 0755); err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	f, err := os.CreateTemp(s.uploadDir, "put-*")
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	body := io.Reader(r.Body)
+	if r.ContentLength >= 0 {
+		body = io.LimitReader(body, r.ContentLength)
+	}
+	_, err = io.Copy(f, body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		target, err = s.place(f.Name(), target)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		if os.IsExist(err) {
+			w.WriteHeader(409)
+			w.Write([]byte("File already exists"))
+			return
+		} else if _, ok := err.(quotaError); ok {
+			w.WriteHeader(413)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		w.WriteHeader(500)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.Header().Set("Location", "/"+target)
+	w.WriteHeader(201)
+	w.Write([]byte("Uploaded"))
+}
+
+//uploadTarget validates an upload of size bytes (-1 when unknown),
+//returning its path relative to the root, or a non-zero status code
+//and message
+func (s *Handler) uploadTarget(dir, name string, size int64) (string, int, string) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", 400, "Invalid filename"
+	}
+	reldir := s.relpath(dir)
+	if s.hidden(reldir) || inTrash(reldir) || inVersions(reldir) {
+		return "", 403, "Cannot upload into this directory"
+	}
+	absdir := filepath.Join(s.c.Directory, filepath.FromSlash(reldir))
+	if info, err := os.Stat(absdir); err != nil || !info.IsDir() {
+		return "", 404, "Directory not found"
+	}
+	if !s.uploadAllowed(name) {
+		return "", 415, "File type not allowed"
+	}
+	target := path.Join(reldir, name)
+	if s.c.UploadOverwrite == overwriteReject {
+		if _, err := os.Lstat(filepath.Join(s.c.Directory, filepath.FromSlash(target))); err == nil {
+			return "", 409, "File already exists"
+		}
+	}
+	if size > 0 && s.quotas() {
+		s.quotaMut.Lock()
+		err := s.quotaCheck(target, size)
+		s.quotaMut.Unlock()
+		if err != nil {
+			return "", 413, err.Error()
+		}
+	}
+	return target, 0, ""
+}
+
+//uploadAllowed checks name against the allowed
+//extensions and MIME types (all allowed when empty)
+func (s *Handler) uploadAllowed(name string) bool {
+	if len(s.c.UploadAllow) == 0 {
+		return true
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	mimetype, _, _ := mime.ParseMediaType(mime.TypeByExtension(ext))
+	for _, allow := range s.c.UploadAllow {
+		allow = strings.ToLower(allow)
+		switch {
+		case strings.HasPrefix(allow, "."):
+			if allow == ext {
+				return true
+			}
+		case strings.HasSuffix(allow, "/*"):
+			if mimetype != "" && strings.HasPrefix(mimetype, strings.TrimSuffix(allow, "*")) {
+				return true
+			}
+		case allow == mimetype:
+			return true
+		}
+	}
+	return false
+}
+
+//place moves the completed upload src into target, according
+//to the overwrite policy, returning the final target, quotas
+//are checked again, as other uploads may have completed
+func (s *Handler) place(src, target string) (placed string, err error) {
+	defer func() {
+		if err == nil {
+			s.invalidate(filepath.Join(s.c.Directory, filepath.FromSlash(placed)))
+		}
+	}()
+	if s.quotas() {
+		var info os.FileInfo
+		if info, err = os.Stat(src); err != nil {
+			return "", err
+		}
+		s.quotaMut.Lock()
+		defer s.quotaMut.Unlock()
+		if err = s.quotaCheck(target, info.Size()); err != nil {
+			return "", err
+		}
+		delta := info.Size() - s.replaced(target)
+		defer func() {
+			if err == nil {
+				s.quotaAdd(path.Dir(placed), delta)
+			}
+		}()
+	}
+	dst := filepath.Join(s.c.Directory, filepath.FromSlash(target))
+	info, err := os.Lstat(dst)
+	if err != nil {
+		return target, os.Rename(src, dst)
+	}
+	if info.IsDir() {
+		return "", os.ErrExist
+	}
+	switch s.c.UploadOverwrite {
+	case overwriteReplace:
+	case overwriteRename:
+		ext := path.Ext(target)
+		base := strings.TrimSuffix(target, ext)
+		for i := 1; ; i++ {
+			t := base + "-" + strconv.Itoa(i) + ext
+			if _, err := os.Lstat(filepath.Join(s.c.Directory, filepath.FromSlash(t))); os.IsNotExist(err) {
+				target = t
+				dst = filepath.Join(s.c.Directory, filepath.FromSlash(t))
+				break
+			} else if err != nil {
+				return "", err
+			}
+		}
+	case overwriteVersion:
+		if err := s.version(target, info); err != nil {
+			return "", err
+		}
+	default:
+		return "", os.ErrExist
+	}
+	return target, os.Rename(src, dst)
+}
+
+//version moves the current target into the versions
+//directory, named by its modified time
+func (s *Handler) version(target string, info os.FileInfo) error {
+	dir := filepath.Join(s.c.Directory, versionsDir, filepath.FromSlash(target))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	stamp := info.ModTime().Format("2006-01-02_15-04-05")
+	name := path.Base(target)
+	for i := 0; ; i++ {
+		v := stamp + "_" + name
+		if i > 0 {
+			v = fmt.Sprintf("%s_%d_%s", stamp, i, name)
+		}
+		dst := filepath.Join(dir, v)
+		if _, err := os.Lstat(dst); os.IsNotExist(err) {
+			defer s.invalidate(dst)
+			return os.Rename(filepath.Join(s.c.Directory, filepath.FromSlash(target)), dst)
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+//inVersions reports whether rel points into the versions directory
+func inVersions(rel string) bool {
+	return rel == versionsDir || strings.HasPrefix(rel, versionsDir+"/")
+}
+
+//quotaRefresh is how long the measured usage of a
+//directory is trusted before it is walked again
+const quotaRefresh = time.Minute
+
+//quotaError is returned when an upload would exceed a quota
+type quotaError string
+
+func (q quotaError) Error() string {
+	return string(q)
+}
+
+//dirUsage is the measured size of a directory
+type dirUsage struct {
+	size     int64
+	measured time.Time
+}
+
+func (s *Handler) quotas() bool {
+	return s.c.UploadQuota > 0 || s.c.UploadDirQuota > 0
+}
+
+//quotaCheck checks an upload of size bytes to target
+//against the quotas (s.quotaMut must be held)
+func (s *Handler) quotaCheck(target string, size int64) error {
+	size -= s.replaced(target)
+	if s.c.UploadQuota > 0 && s.usage("")+size > int64(s.c.UploadQuota) {
+		return quotaError("Upload would exceed the quota of " + s.c.UploadQuota.String())
+	}
+	if s.c.UploadDirQuota > 0 && s.usage(s.relpath(path.Dir(target)))+size > int64(s.c.UploadDirQuota) {
+		return quotaError("Upload would exceed the directory quota of " + s.c.UploadDirQuota.String())
+	}
+	return nil
+}
+
+//replaced returns the size of the file an upload to target
+//would replace, previous versions aren't counted
+func (s *Handler) replaced(target string) int64 {
+	if s.c.UploadOverwrite != overwriteReplace && s.c.UploadOverwrite != overwriteVersion {
+		return 0
+	}
+	info, err := os.Lstat(filepath.Join(s.c.Directory, filepath.FromSlash(target)))
+	if err != nil || !info.Mode().IsRegular() {
+		return 0
+	}
+	return info.Size()
+}
+
+//usage returns the total size of the files within reldir, it is
+//walked when not measured recently (s.quotaMut must be held)
+func (s *Handler) usage(reldir string) int64 {
+	if u, ok := s.usages[reldir]; ok && time.Since(u.measured) < quotaRefresh {
+		return u.size
+	}
+	size := s.diskUsage(filepath.Join(s.c.Directory, filepath.FromSlash(reldir)))
+	s.usages[reldir] = &dirUsage{size: size, measured: time.Now()}
+	return size
+}
+
+//quotaAdd adjusts the usage of reldir, and of each
+//measured directory containing it (s.quotaMut must be held)
+func (s *Handler) quotaAdd(reldir string, delta int64) {
+	reldir = s.relpath(reldir)
+	for dir, u := range s.usages {
+		if dir == "" || dir == reldir || strings.HasPrefix(reldir, dir+"/") {
+			u.size += delta
+		}
+	}
+}
+
+//unquota forgets the usage of each directory containing rel
+func (s *Handler) unquota(rel string) {
+	if !s.quotas() {
+		return
+	}
+	s.quotaMut.Lock()
+	defer s.quotaMut.Unlock()
+	for dir := range s.usages {
+		if dir == "" || dir == rel || strings.HasPrefix(rel, dir+"/") {
+			delete(s.usages, dir)
+		}
+	}
+}
+
+//diskUsage returns the total size of all files within dir, except
+//for partial uploads, the trash and previous versions
+func (s *Handler) diskUsage(dir string) int64 {
+	total := int64(0)
+	filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if s.internal(p) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
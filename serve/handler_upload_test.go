@@ -0,0 +1,107 @@
+package serve
+
+import (
+	"encoding/base64"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jpillora/sizestr"
+)
+
+func TestUploadOverwrite(t *testing.T) {
+	for _, test := range []struct {
+		policy string
+		code   int
+		want   map[string]string
+	}{
+		{overwriteReject, 409, map[string]string{"f.txt": "old"}},
+		{overwriteReplace, 201, map[string]string{"f.txt": "new"}},
+		{overwriteRename, 201, map[string]string{"f.txt": "old", "f-1.txt": "new"}},
+		{overwriteVersion, 201, map[string]string{"f.txt": "new", ".versions/f.txt/*": "old"}},
+	} {
+		dir := t.TempDir()
+		os.WriteFile(filepath.Join(dir, "f.txt"), []byte("old"), 0644)
+		h, err := NewHandler(Config{Directory: dir, Auth: "u:p", Write: true, Upload: true, UploadOverwrite: test.policy, Quiet: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest("PUT", "/f.txt", strings.NewReader("new"))
+		r.SetBasicAuth("u", "p")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		h.Close()
+		if w.Code != test.code {
+			t.Errorf("%s: got %d, want %d", test.policy, w.Code, test.code)
+		}
+		for glob, want := range test.want {
+			names, _ := filepath.Glob(filepath.Join(dir, filepath.FromSlash(glob)))
+			if len(names) != 1 {
+				t.Errorf("%s: expected one %s, got %d", test.policy, glob, len(names))
+				continue
+			}
+			if b, _ := os.ReadFile(names[0]); string(b) != want {
+				t.Errorf("%s: %s is %q, want %q", test.policy, glob, b, want)
+			}
+		}
+	}
+}
+
+func TestUploadQuota(t *testing.T) {
+	dir := t.TempDir()
+	h, err := NewHandler(Config{Directory: dir, Auth: "u:p", Write: true, Trash: true, Upload: true, UploadOverwrite: overwriteVersion, UploadQuota: sizestr.Bytes(10), Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	do := func(method, url, body string, headers ...string) int {
+		r := httptest.NewRequest(method, url, strings.NewReader(body))
+		r.SetBasicAuth("u", "p")
+		r.Header.Set("X-Requested-With", "XMLHttpRequest")
+		for i := 0; i < len(headers); i += 2 {
+			r.Header.Set(headers[i], headers[i+1])
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+	for _, test := range []struct {
+		name, method, url, body string
+		code                    int
+	}{
+		{"within", "PUT", "/a.txt", "123456", 201},
+		{"exceeds", "PUT", "/b.txt", "123456", 413},
+		{"replaces", "PUT", "/a.txt", "12345678", 201},
+		{"exceeds again", "PUT", "/b.txt", "123", 413},
+		{"deleted into trash", "DELETE", "/a.txt", "", 200},
+		{"freed", "PUT", "/b.txt", "123456", 201},
+	} {
+		if code := do(test.method, test.url, test.body); code != test.code {
+			t.Errorf("%s: got %d, want %d", test.name, code, test.code)
+		}
+	}
+	//concurrent uploads which each fit are checked again on completion
+	octet := "application/offset+octet-stream"
+	locs := []string{}
+	for _, name := range []string{"c.txt", "d.txt"} {
+		r := httptest.NewRequest("POST", tusPath, nil)
+		r.SetBasicAuth("u", "p")
+		r.Header.Set("Tus-Resumable", tusVersion)
+		r.Header.Set("Upload-Length", "3")
+		r.Header.Set("Upload-Metadata", "filename "+base64.StdEncoding.EncodeToString([]byte(name)))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != 201 {
+			t.Fatalf("create %s: %d %s", name, w.Code, w.Body)
+		}
+		locs = append(locs, w.Header().Get("Location"))
+	}
+	if code := do("PATCH", locs[0], "123", "Tus-Resumable", tusVersion, "Upload-Offset", "0", "Content-Type", octet); code != 204 {
+		t.Errorf("first tus upload: got %d", code)
+	}
+	if code := do("PATCH", locs[1], "123", "Tus-Resumable", tusVersion, "Upload-Offset", "0", "Content-Type", octet); code != 413 {
+		t.Errorf("second tus upload: got %d, want 413", code)
+	}
+}
@@ -0,0 +1,96 @@
+package serve
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+//upstream is a server which requests are proxied to
+type upstream struct {
+	url    *url.URL
+	socket string
+	once   sync.Once
+	rt     http.RoundTripper
+}
+
+//parseUpstream parses an http:// or https:// url, or a unix
+//socket in the form unix:///path/to.sock, optionally followed by
+//a path on the upstream server (unix:///path/to.sock:/prefix/)
+func parseUpstream(raw string) (*upstream, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https":
+		if u.Host == "" {
+			return nil, fmt.Errorf("Invalid upstream: %s (missing host)", raw)
+		}
+		return &upstream{url: u}, nil
+	case "unix":
+		socket, path := u.Host+u.Path, ""
+		if i := strings.Index(socket, ":"); i >= 0 {
+			socket, path = socket[:i], socket[i+1:]
+		}
+		if socket == "" {
+			return nil, fmt.Errorf("Invalid upstream: %s (missing socket path)", raw)
+		}
+		return &upstream{
+			url:    &url.URL{Scheme: "http", Host: "localhost", Path: path, RawQuery: u.RawQuery},
+			socket: socket,
+		}, nil
+	}
+	return nil, fmt.Errorf("Invalid upstream: %s (must be http://, https:// or unix://)", raw)
+}
+
+//transport returns the round tripper which reaches the upstream
+func (up *upstream) transport() http.RoundTripper {
+	up.once.Do(func() {
+		up.rt = http.DefaultTransport
+		if up.socket != "" {
+			t := http.DefaultTransport.(*http.Transport).Clone()
+			t.Proxy = nil
+			t.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", up.socket)
+			}
+			up.rt = t
+		}
+	})
+	return up.rt
+}
+
+//newProxy creates a reverse proxy to the upstream which streams
+//responses without buffering (including Server-Sent Events and
+//upgraded connections, such as WebSockets), the Host header is
+//swapped for the upstream's, as is the Origin header when it was
+//this server (unix sockets have no host, so neither are swapped)
+func newProxy(up *upstream) *httputil.ReverseProxy {
+	target := up.url
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.FlushInterval = -1
+	proxy.Transport = up.transport()
+	director := proxy.Director
+	proxy.Director = func(r *http.Request) {
+		host := r.Host
+		director(r)
+		if r.Header.Get("X-Forwarded-Host") == "" {
+			r.Header.Set("X-Forwarded-Host", host)
+		}
+		if up.socket != "" {
+			r.Host = host
+			return
+		}
+		if origin, err := url.Parse(r.Header.Get("Origin")); err == nil && origin.Host == host {
+			r.Header.Set("Origin", target.Scheme+"://"+target.Host)
+		}
+		r.Host = target.Host
+	}
+	return proxy
+}
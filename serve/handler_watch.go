@@ -0,0 +1,127 @@
+package serve
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+
+	"gopkg.in/fsnotify.v1"
+)
+
+//watchers
+const (
+	watchAuto   = "auto"
+	watchNotify = "notify"
+	watchPoll   = "poll"
+)
+
+//fileWatcher reports changes within the watched directories
+type fileWatcher interface {
+	Add(dir string) error
+	Remove(dir string) error
+	Events() <-chan fsnotify.Event
+	Close() error
+}
+
+//notifyWatcher is a fileWatcher receiving filesystem events
+type notifyWatcher struct {
+	w *fsnotify.Watcher
+}
+
+func (n notifyWatcher) Add(dir string) error {
+	return n.w.Add(dir)
+}
+
+func (n notifyWatcher) Remove(dir string) error {
+	return n.w.Remove(dir)
+}
+
+func (n notifyWatcher) Events() <-chan fsnotify.Event {
+	return n.w.Events
+}
+
+func (n notifyWatcher) Close() error {
+	return n.w.Close()
+}
+
+//newWatcher creates the configured watcher, auto polls
+//when filesystem events are unavailable
+func (s *Handler) newWatcher() (fileWatcher, error) {
+	if s.c.Watcher == watchPoll || (s.c.Watcher == watchAuto && needsPolling(s.c.Directory)) {
+		return newPollWatcher(s.c.PollInterval), nil
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		if s.c.Watcher == watchAuto {
+			return newPollWatcher(s.c.PollInterval), nil
+		}
+		return nil, err
+	}
+	//errors must be received for events to be delivered,
+	//the channel is closed along with the watcher
+	s.goBackground(func() {
+		for range w.Errors {
+		}
+	})
+	return notifyWatcher{w}, nil
+}
+
+//lrIgnoreDefaults are never watched for live reload
+var lrIgnoreDefaults = []string{".git", ".hg", ".svn", "node_modules", trashDir, versionsDir}
+
+//watch adds dir to the watcher
+func (s *Handler) watch(dir string) {
+	s.watcherMut.Lock()
+	if _, watching := s.watching[dir]; !watching {
+		if err := s.watcher.Add(dir); err == nil {
+			s.watching[dir] = true
+		}
+	}
+	s.watcherMut.Unlock()
+}
+
+//watchTree watches dir and all directories within,
+//except for those which are ignored
+func (s *Handler) watchTree(dir string) {
+	filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if s.ctx.Err() != nil {
+			return s.ctx.Err() //closed
+		}
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if s.ignored(p) {
+			return filepath.SkipDir
+		}
+		s.watch(p)
+		return nil
+	})
+}
+
+//ignored reports whether changes to p should not cause a reload,
+//p or any of its parents match an ignore glob by name or by path
+func (s *Handler) ignored(p string) bool {
+	rel, err := filepath.Rel(s.c.Directory, p)
+	if err != nil || rel == "." {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	for r := rel; r != "."; r = path.Dir(r) {
+		for _, glob := range s.lrIgnore {
+			if globMatch(glob, r) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//globMatch reports whether glob matches the
+//slash separated rel by name or by path
+func globMatch(glob, rel string) bool {
+	if ok, _ := path.Match(glob, path.Base(rel)); ok {
+		return true
+	}
+	ok, _ := path.Match(glob, rel)
+	return ok
+}
@@ -0,0 +1,26 @@
+package serve
+
+import "syscall"
+
+//filesystems which do not deliver inotify events
+//for changes made elsewhere (see statfs(2))
+var pollFilesystems = map[uint32]string{
+	0x6969:     "nfs",
+	0x517b:     "smb",
+	0xff534d42: "cifs",
+	0xfe534d42: "smb2",
+	0x786f4256: "vboxsf",
+	0x01021997: "9p",
+	0x65735546: "fuse",
+}
+
+//needsPolling reports whether dir is on a network
+//or shared filesystem
+func needsPolling(dir string) bool {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return false
+	}
+	_, ok := pollFilesystems[uint32(st.Type)]
+	return ok
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package serve
+
+//needsPolling reports whether dir is on a network
+//or shared filesystem (only detected on linux)
+func needsPolling(dir string) bool {
+	return false
+}
@@ -0,0 +1,151 @@
+package serve
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gopkg.in/fsnotify.v1"
+)
+
+//pollEntry is the state of a file when last scanned
+type pollEntry struct {
+	size  int64
+	mtime time.Time
+}
+
+//pollWatcher detects changes by comparing the size and modified
+//time of each file within the watched directories, the interval
+//is waited after each scan completes, bounding its CPU use
+type pollWatcher struct {
+	interval time.Duration
+	mut      sync.Mutex
+	dirs     map[string]map[string]pollEntry
+	events   chan fsnotify.Event
+	done     chan struct{}
+	once     sync.Once
+}
+
+func newPollWatcher(interval time.Duration) *pollWatcher {
+	w := &pollWatcher{
+		interval: interval,
+		dirs:     map[string]map[string]pollEntry{},
+		events:   make(chan fsnotify.Event, 64),
+		done:     make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *pollWatcher) Add(dir string) error {
+	snap, err := pollSnapshot(dir)
+	if err != nil {
+		return err
+	}
+	w.mut.Lock()
+	w.dirs[dir] = snap
+	w.mut.Unlock()
+	return nil
+}
+
+func (w *pollWatcher) Remove(dir string) error {
+	w.mut.Lock()
+	delete(w.dirs, dir)
+	w.mut.Unlock()
+	return nil
+}
+
+func (w *pollWatcher) Events() <-chan fsnotify.Event {
+	return w.events
+}
+
+func (w *pollWatcher) Close() error {
+	w.once.Do(func() {
+		close(w.done)
+	})
+	return nil
+}
+
+func (w *pollWatcher) run() {
+	defer close(w.events)
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-time.After(w.interval):
+		}
+		w.scan()
+	}
+}
+
+//scan compares each directory with its previous snapshot
+func (w *pollWatcher) scan() {
+	w.mut.Lock()
+	dirs := make([]string, 0, len(w.dirs))
+	for dir := range w.dirs {
+		dirs = append(dirs, dir)
+	}
+	w.mut.Unlock()
+	for _, dir := range dirs {
+		next, err := pollSnapshot(dir)
+		w.mut.Lock()
+		prev, ok := w.dirs[dir]
+		if ok {
+			if err != nil {
+				delete(w.dirs, dir)
+			} else {
+				w.dirs[dir] = next
+			}
+		}
+		w.mut.Unlock()
+		if !ok {
+			continue //removed during the scan
+		}
+		if err != nil {
+			w.send(dir, fsnotify.Remove)
+			continue
+		}
+		for name, e := range next {
+			if old, ok := prev[name]; !ok {
+				w.send(filepath.Join(dir, name), fsnotify.Create)
+			} else if old.size != e.size || !old.mtime.Equal(e.mtime) {
+				w.send(filepath.Join(dir, name), fsnotify.Write)
+			}
+		}
+		for name := range prev {
+			if _, ok := next[name]; !ok {
+				w.send(filepath.Join(dir, name), fsnotify.Remove)
+			}
+		}
+	}
+}
+
+func (w *pollWatcher) send(name string, op fsnotify.Op) {
+	select {
+	case w.events <- fsnotify.Event{Name: name, Op: op}:
+	case <-w.done:
+	}
+}
+
+//pollSnapshot reads the state of each entry in dir
+func pollSnapshot(dir string) (map[string]pollEntry, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	names, err := f.Readdirnames(-1)
+	if err != nil {
+		return nil, err
+	}
+	snap := make(map[string]pollEntry, len(names))
+	for _, name := range names {
+		info, err := os.Lstat(filepath.Join(dir, name))
+		if err != nil {
+			continue //removed since read
+		}
+		snap[name] = pollEntry{size: info.Size(), mtime: info.ModTime()}
+	}
+	return snap, nil
+}
@@ -0,0 +1,81 @@
+package serve
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gopkg.in/fsnotify.v1"
+)
+
+func TestPollWatcher(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	os.WriteFile(a, []byte("a"), 0644)
+	w := newPollWatcher(10 * time.Millisecond)
+	defer w.Close()
+	if err := w.Add(dir); err != nil {
+		t.Fatal(err)
+	}
+	expect := func(name string, op fsnotify.Op) {
+		t.Helper()
+		select {
+		case e := <-w.Events():
+			if e.Name != name || e.Op != op {
+				t.Fatalf("expected %s %s, got %s", op, name, e)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected %s %s, got nothing", op, name)
+		}
+	}
+	os.WriteFile(a, []byte("aa"), 0644)
+	expect(a, fsnotify.Write)
+	b := filepath.Join(dir, "b.txt")
+	os.WriteFile(b, []byte("b"), 0644)
+	expect(b, fsnotify.Create)
+	os.Remove(a)
+	expect(a, fsnotify.Remove)
+}
+
+func TestNewWatcher(t *testing.T) {
+	dir := t.TempDir()
+	if needsPolling(dir) {
+		t.Skip("temporary directory is on a polled filesystem")
+	}
+	for _, test := range []struct {
+		watcher string
+		poll    bool
+	}{
+		{watchPoll, true},
+		{watchNotify, false},
+		{watchAuto, false},
+	} {
+		h, err := NewHandler(Config{Directory: dir, Quiet: true, ListCache: true, Watcher: test.watcher})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, poll := h.watcher.(*pollWatcher); poll != test.poll {
+			t.Errorf("%s: got %T", test.watcher, h.watcher)
+		}
+		h.Close()
+	}
+	//changes found by polling are reloaded
+	h, err := NewHandler(Config{
+		Directory:    dir,
+		Quiet:        true,
+		LiveReload:   true,
+		Watcher:      watchPoll,
+		PollInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	events := lrListen(t, h)
+	lrWatching(t, h, dir)
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644)
+	if e := lrExpect(t, events); e.data != `{"path":"/a.txt","action":"reload"}` {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+}
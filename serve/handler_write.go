@@ -0,0 +1,258 @@
+package serve
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//trashDir is the directory within the root which
+//receives deleted files when trash is enabled
+const trashDir = ".trash"
+
+//trashOrigin is written into each trash entry and
+//records the path the entry should be restored to
+const trashOrigin = ".origin"
+
+//fileop handles file management requests (DELETE and
+//POST ?action=), returning false when r is not one, operations
+//on missing paths are sent to the fallback (when allowed) if its
+//triggers match
+func (s *Handler) fileop(w http.ResponseWriter, r *http.Request, fallback bool) bool {
+	//shorthand
+	reply := func(c int, msg string) {
+		w.WriteHeader(c)
+		if msg != "" {
+			w.Write([]byte(msg))
+		}
+	}
+	action := r.URL.Query().Get("action")
+	if r.Method == "DELETE" {
+		action = "delete"
+	} else if r.Method == "PUT" && s.c.Upload {
+		//missing directories may be handled by the fallback
+		dir := filepath.Join(s.c.Directory, filepath.FromSlash(path.Dir(s.relpath(r.URL.Path))))
+		if _, err := os.Stat(dir); err != nil && fallback && s.fallback != nil && s.fallbackOn.match(r, true, false) {
+			s.serveFallback(w, r)
+			return true
+		}
+		if crossSite(r) {
+			reply(403, "Cross site request rejected")
+			return true
+		}
+		s.put(w, r)
+		return true
+	} else if r.Method != "POST" || action == "" {
+		return false
+	}
+	rel := s.relpath(r.URL.Path)
+	if rel == "" && action != "mkdir" {
+		reply(403, "Cannot modify the root directory")
+		return true
+	}
+	if s.hidden(rel) {
+		reply(404, "Not found")
+		return true
+	}
+	p := filepath.Join(s.c.Directory, filepath.FromSlash(rel))
+	info, err := os.Stat(p)
+	if err != nil {
+		if fallback && s.fallback != nil && s.fallbackOn.match(r, true, false) {
+			s.serveFallback(w, r)
+			return true
+		}
+		reply(404, "Not found")
+		return true
+	}
+	if crossSite(r) {
+		reply(403, "Cross site request rejected")
+		return true
+	}
+	switch action {
+	case "delete":
+		if s.c.Trash && !inTrash(rel) {
+			err = s.trash(rel)
+		} else {
+			err = os.RemoveAll(p)
+		}
+		if err != nil {
+			reply(500, err.Error())
+			return true
+		}
+		s.unquota(rel)
+		s.invalidate(p)
+		if s.c.Trash {
+			s.invalidate(filepath.Join(s.c.Directory, trashDir))
+		}
+		reply(200, "Deleted")
+	case "mkdir":
+		name := r.URL.Query().Get("name")
+		if !info.IsDir() {
+			reply(400, "Not a directory")
+			return true
+		}
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+			reply(400, "Invalid folder name")
+			return true
+		}
+		if err := os.Mkdir(filepath.Join(p, name), 0755); os.IsExist(err) {
+			reply(409, "Already exists")
+			return true
+		} else if err != nil {
+			reply(500, err.Error())
+			return true
+		}
+		s.invalidate(filepath.Join(p, name))
+		reply(201, "Created")
+	case "move":
+		to := s.relpath(r.URL.Query().Get("to"))
+		if to == "" {
+			reply(400, "Invalid destination")
+			return true
+		}
+		if to == rel || strings.HasPrefix(to, rel+"/") {
+			reply(400, "Cannot move a directory into itself")
+			return true
+		}
+		if s.hidden(to) || inTrash(to) || inVersions(to) {
+			reply(403, "Invalid destination")
+			return true
+		}
+		dst := filepath.Join(s.c.Directory, filepath.FromSlash(to))
+		if _, err := os.Lstat(dst); err == nil {
+			reply(409, "Destination already exists")
+			return true
+		}
+		if err := os.Rename(p, dst); err != nil {
+			reply(500, err.Error())
+			return true
+		}
+		s.unquota(rel)
+		s.unquota(to)
+		s.invalidate(p)
+		s.invalidate(dst)
+		reply(200, "Moved")
+	case "restore":
+		if !s.c.Trash || path.Dir(rel) != trashDir {
+			reply(400, "Not a trash entry")
+			return true
+		}
+		origin, err := s.restore(rel)
+		if os.IsExist(err) {
+			reply(409, "Original path already exists")
+			return true
+		} else if err != nil {
+			reply(500, err.Error())
+			return true
+		}
+		s.unquota(origin)
+		s.invalidate(p)
+		s.invalidate(filepath.Join(s.c.Directory, filepath.FromSlash(origin)))
+		reply(200, "Restored")
+	default:
+		reply(400, "Unknown action")
+	}
+	return true
+}
+
+//crossSite reports whether r may have been sent by another site's
+//page, the browser's Origin and Sec-Fetch-Site headers are checked,
+//and POSTs (which forms may send) require the X-Requested-With
+//header, which other sites cannot send without CORS
+func crossSite(r *http.Request) bool {
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" && site != "same-origin" && site != "none" {
+		return true
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+			return true
+		}
+	}
+	return r.Method == "POST" && r.Header.Get("X-Requested-With") == ""
+}
+
+//relpath converts a URL path into a clean, slash
+//separated path relative to the root ("" is the root)
+func (s *Handler) relpath(urlpath string) string {
+	return strings.TrimPrefix(path.Clean("/"+urlpath), "/")
+}
+
+//inTrash reports whether rel points into the trash
+func inTrash(rel string) bool {
+	return rel == trashDir || strings.HasPrefix(rel, trashDir+"/")
+}
+
+//internal reports whether the path p is within one of the
+//directories managed by serve: partial uploads, the
+//trash and previous versions
+func (s *Handler) internal(p string) bool {
+	rel, err := filepath.Rel(s.c.Directory, p)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	return s.hidden(rel) || inTrash(rel) || inVersions(rel)
+}
+
+//trash moves rel into a new trash entry, along
+//with an origin file so it may later be restored
+func (s *Handler) trash(rel string) error {
+	base := path.Base(rel)
+	stamp := time.Now().Format("2006-01-02_15-04-05")
+	var entry string
+	for i := 0; ; i++ {
+		id := stamp + "_" + base
+		if i > 0 {
+			id = fmt.Sprintf("%s_%d_%s", stamp, i, base)
+		}
+		entry = filepath.Join(s.c.Directory, trashDir, id)
+		if err := os.MkdirAll(filepath.Dir(entry), 0755); err != nil {
+			return err
+		}
+		err := os.Mkdir(entry, 0755)
+		if err == nil {
+			break
+		} else if !os.IsExist(err) {
+			return err
+		}
+	}
+	if err := os.WriteFile(filepath.Join(entry, trashOrigin), []byte(rel), 0644); err != nil {
+		os.RemoveAll(entry)
+		return err
+	}
+	src := filepath.Join(s.c.Directory, filepath.FromSlash(rel))
+	if err := os.Rename(src, filepath.Join(entry, base)); err != nil {
+		os.RemoveAll(entry)
+		return err
+	}
+	return nil
+}
+
+//restore moves a trash entry back to its origin, returning the origin
+func (s *Handler) restore(rel string) (string, error) {
+	entry := filepath.Join(s.c.Directory, filepath.FromSlash(rel))
+	b, err := os.ReadFile(filepath.Join(entry, trashOrigin))
+	if err != nil {
+		return "", err
+	}
+	origin := s.relpath(string(b))
+	if origin == "" || inTrash(origin) {
+		return "", fmt.Errorf("Invalid origin: %s", b)
+	}
+	dst := filepath.Join(s.c.Directory, filepath.FromSlash(origin))
+	if _, err := os.Lstat(dst); err == nil {
+		return "", os.ErrExist
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(filepath.Join(entry, path.Base(origin)), dst); err != nil {
+		return "", err
+	}
+	return origin, os.RemoveAll(entry)
+}
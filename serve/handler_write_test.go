@@ -0,0 +1,170 @@
+package serve
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileOps(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(dir, "b#1.txt"), []byte("b"), 0644)
+	h, err := NewHandler(Config{Directory: dir, Auth: "u:p", Write: true, Trash: true, Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	do := func(method, url string, headers ...string) int {
+		r := httptest.NewRequest(method, url, nil)
+		r.SetBasicAuth("u", "p")
+		r.Header.Set("X-Requested-With", "XMLHttpRequest")
+		for i := 0; i < len(headers); i += 2 {
+			r.Header.Set(headers[i], headers[i+1])
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+	exists := func(rel string) bool {
+		_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(rel)))
+		return err == nil
+	}
+	for _, test := range []struct {
+		name         string
+		method, url  string
+		headers      []string
+		code         int
+		gone, exists string
+	}{
+		{"cross site", "POST", "/a.txt?action=delete", []string{"Origin", "http://evil.example"}, 403, "", "a.txt"},
+		{"cross site fetch", "DELETE", "/a.txt", []string{"Sec-Fetch-Site", "cross-site"}, 403, "", "a.txt"},
+		{"no marker", "POST", "/a.txt?action=delete", []string{"X-Requested-With", ""}, 403, "", "a.txt"},
+		{"mkdir", "POST", "/?action=mkdir&name=d", nil, 201, "", "d"},
+		{"mkdir exists", "POST", "/?action=mkdir&name=d", nil, 409, "", "d"},
+		{"move", "POST", "/a.txt?action=move&to=/d/a.txt", nil, 200, "a.txt", "d/a.txt"},
+		{"move into trash", "POST", "/d/a.txt?action=move&to=/.trash/a.txt", nil, 403, "", "d/a.txt"},
+		{"move into itself", "POST", "/d?action=move&to=/d/e", nil, 400, "", "d"},
+		{"delete escaped", "DELETE", "/b%231.txt", nil, 200, "b#1.txt", ""},
+		{"delete missing", "DELETE", "/missing.txt", nil, 404, "", ""},
+		{"delete root", "DELETE", "/", nil, 403, "", ""},
+	} {
+		if code := do(test.method, test.url, test.headers...); code != test.code {
+			t.Errorf("%s: got %d, want %d", test.name, code, test.code)
+		}
+		if test.gone != "" && exists(test.gone) {
+			t.Errorf("%s: %s still exists", test.name, test.gone)
+		}
+		if test.exists != "" && !exists(test.exists) {
+			t.Errorf("%s: %s is missing", test.name, test.exists)
+		}
+	}
+	//restore the trashed file
+	entries, _ := filepath.Glob(filepath.Join(dir, trashDir, "*"))
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 trash entry, got %d", len(entries))
+	}
+	entry := "/" + trashDir + "/" + filepath.Base(entries[0])
+	if code := do("POST", entry+"?action=restore"); code != 200 || !exists("b#1.txt") || exists(entry) {
+		t.Errorf("restore: got %d", code)
+	}
+	if code := do("POST", "/d/a.txt?action=restore"); code != 400 {
+		t.Errorf("restore outside trash: got %d", code)
+	}
+}
+
+func TestFileOpsFallback(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("upstream " + r.Method))
+	}))
+	defer upstream.Close()
+	h, err := NewHandler(Config{Directory: t.TempDir(), Auth: "u:p", Write: true, Upload: true, Quiet: true, Fallback: []string{upstream.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	for _, method := range []string{"DELETE", "PUT"} {
+		r := httptest.NewRequest(method, "/api/users/1", nil)
+		r.SetBasicAuth("u", "p")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != 200 || w.Body.String() != "upstream "+method {
+			t.Errorf("%s: got %d %q", method, w.Code, w.Body)
+		}
+	}
+}
+
+func TestFileOpsFallbackFirst(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(404)
+		w.Write([]byte("upstream not found"))
+	}))
+	defer upstream.Close()
+	dir := t.TempDir()
+	h, err := NewHandler(Config{Directory: dir, Auth: "u:p", Write: true, Upload: true, Quiet: true, Fallback: []string{upstream.URL}, FallbackFirst: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	put := func(name string, body []byte, chunked bool) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("PUT", "/"+name, bytes.NewReader(body))
+		if chunked {
+			r.ContentLength = -1
+		}
+		r.SetBasicAuth("u", "p")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+	//the body read by the fallback is written
+	for _, chunked := range []bool{false, true} {
+		os.Remove(filepath.Join(dir, "f.txt"))
+		if w := put("f.txt", []byte("hello"), chunked); w.Code != 201 {
+			t.Fatalf("got %d %q", w.Code, w.Body)
+		}
+		if b, _ := os.ReadFile(filepath.Join(dir, "f.txt")); string(b) != "hello" {
+			t.Fatalf("got file %q", b)
+		}
+		//larger bodies aren't kept, the 404 is passed through
+		w := put("big.bin", make([]byte, fallbackMaxRetry+1), chunked)
+		if w.Code != 404 || w.Body.String() != "upstream not found" {
+			t.Fatalf("got %d %q", w.Code, w.Body)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "big.bin")); err == nil {
+			t.Fatal("large body was written")
+		}
+	}
+}
+
+func TestFileOpsListCache(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644)
+	//the watcher won't report the change in time
+	h, err := NewHandler(Config{Directory: dir, Auth: "u:p", Write: true, ListCache: true, DirSizes: true, Watcher: watchPoll, PollInterval: time.Hour, Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	do := func(method, url string) string {
+		r := httptest.NewRequest(method, url, nil)
+		r.SetBasicAuth("u", "p")
+		r.Header.Set("X-Requested-With", "XMLHttpRequest")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Body.String()
+	}
+	if list := do("GET", "/"); list != "a.txt\n" {
+		t.Fatalf("listing: %q", list)
+	}
+	do("POST", "/?action=mkdir&name=d")
+	do("DELETE", "/a.txt")
+	if list := do("GET", "/"); list != "d\n" {
+		t.Fatalf("listing after changes: %q", list)
+	}
+}
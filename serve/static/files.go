@@ -1,6 +1,7 @@
 // Code generated by go-bindata.
 // sources:
 // static/list.html
+// static/livereload.js
 // DO NOT EDIT!
 
 package static
@@ -68,7 +69,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var _staticListHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xa4\x55\x51\x6b\xdb\x30\x10\x7e\xb6\x7f\xc5\x61\xe8\x5b\x6d\xa7\x94\xc1\xf0\x54\x43\x59\x19\xec\xa5\x0c\xb6\x3f\x70\x89\x2e\xb1\xa8\x2c\x19\x49\x5d\x9a\x18\xff\xf7\x21\xc9\x49\x9d\xd4\xcb\xb6\xee\x29\xc9\xe9\xbb\xfb\xbe\xef\x4e\xba\xb0\xc6\xb5\xb2\x4e\x53\xd6\x10\xf2\x3a\x4d\x98\x13\x4e\x52\xdd\xf7\x50\x7c\x43\xd7\xc0\x30\xb0\x32\x86\xd2\x84\x59\xb7\x0b\x5f\x12\x9f\x74\x9d\x26\xc9\x52\xf3\x1d\xf4\x69\x92\x24\x0d\x89\x4d\xe3\x2a\xb8\x59\x2c\xae\x3e\xf9\xc0\x56\x70\xd7\x4c\x7e\xaf\xb5\x72\xf9\x1a\x5b\x21\x77\x15\x7c\xd6\xcf\x46\x90\xb9\x86\x56\x2b\x6d\x3b\x5c\x91\xc7\x0c\x69\x9a\x24\x18\xeb\x39\x7a\x71\x39\xa7\x95\x36\xe8\x84\x56\x15\x28\xad\x5e\x41\x0e\x97\x92\x22\xb0\x45\xb3\x11\xaa\x82\x0f\x57\xc7\xd3\xa2\xf3\xca\xfb\x23\x6b\x90\x5d\xc1\xb3\xe2\x64\xa4\x98\x94\x29\x14\xb6\x34\xe1\x43\x29\x36\xaa\x02\xe3\xad\x04\xd1\x1d\x72\x2e\xd4\x26\x37\xd1\xdc\xed\xa2\x7b\x39\x4b\x1e\xe5\x6e\xb5\xe1\xf9\xd6\x60\x57\xc1\xd2\x10\x3e\xe5\x3e\x10\x4a\x70\x61\x3b\x89\xbb\x0a\x84\xf2\xdc\xf9\x52\xea\xd5\xd3\xb4\x43\xb7\x8b\x93\xaa\x56\xec\x67\x24\x49\x5a\xbb\x57\x0c\x9a\x55\x23\x7e\xd2\xd4\xa2\xd8\x53\x05\x8b\xe2\x23\xb5\x11\x96\xb0\x72\x9c\x16\x2b\xe3\x68\x53\xe6\xa7\x15\x46\xec\xbb\xe7\xc7\xc8\x9c\xf1\x1f\x09\x73\x0d\xac\x24\x5a\x7b\x97\x79\x53\x59\xfd\x88\x2d\xb1\xd2\x35\xe7\xa7\x9e\x26\xab\xbf\x8b\xfd\xf1\x94\x95\xb1\x06\x73\xe6\x00\x5a\x0b\x49\x20\x1c\xb5\xd9\x98\xce\x4f\x8b\xfb\x60\xc2\x10\x1a\x43\xeb\xbb\xac\x9c\x5c\xb5\xac\x2e\x58\x89\x31\xab\x74\xfc\x3c\x3d\xb2\xe7\x87\xa3\x03\x75\xdf\x8b\x35\x28\xf2\x55\x0c\x29\x07\x59\x36\x0c\xff\xa3\x28\x0a\x0a\xa5\x82\xa4\x7f\xd6\xd4\xf7\xa4\xf8\x30\x40\xdf\x1b\x54\x1b\x82\xe2\x8b\x90\x64\xdf\x25\x2a\x78\x2b\xee\x57\x2b\xb2\x56\x2c\x25\x85\x22\x6f\xb4\x86\xe6\x45\xe8\x57\xfb\x20\xcc\x30\x94\xa3\x86\x2c\xbc\x64\x3f\xce\xf0\x92\xb1\x86\xbe\x27\x69\x29\xa8\x3b\x1e\xc0\x88\xbe\x68\x12\x50\xba\x48\xe8\xe7\xef\xb3\x96\x3b\x47\xf6\x44\xe8\xc8\x9e\x47\x12\xf0\x73\xd1\xee\x54\x7f\x7e\x10\xd0\xf7\xe0\x74\xb8\xed\x87\x82\x33\x2a\xce\xfa\xe9\x39\x1e\x9f\xdb\xdf\xf6\xd3\x66\xf3\xf7\x79\x94\x38\xc9\x05\x0f\x3f\xde\x9c\x43\x1c\x6e\x86\xc1\x9e\xca\x98\x7f\x03\xaf\xcd\xf8\xa1\x1d\xca\xf9\x8e\x1c\xfd\x4d\x31\xd3\xb2\xf3\xee\x1e\x84\x79\xaf\xb9\x98\x0a\x5c\x98\xa9\x35\x1f\xbd\xe4\xac\xfe\x83\xa6\xfb\xb8\x6e\xce\x35\x8d\x5b\xe8\x92\x2a\xae\xb7\x4a\x6a\xe4\x80\x52\x02\xda\x59\xe2\xf9\x55\x50\xec\x45\x97\xd5\x7b\xd1\xf9\x4b\x7b\x7d\x01\xe7\xd0\x64\xb5\x43\xf3\x17\xb8\x62\xb3\x0f\xd0\x62\xb3\x9f\x3c\xe9\x37\xae\xfd\xee\x1c\x57\x24\x2b\xe3\xce\xf4\x4b\xd4\xff\x4f\xfe\x0a\x00\x00\xff\xff\xcf\xe4\x2d\xe8\x2e\x07\x00\x00")
+var _staticListHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xbc\x5a\x7b\x8f\xdb\xb6\xb2\xff\x5b\xfe\x14\x13\xe2\x36\xd7\xbe\xb1\x65\x6f\x92\x16\x85\x63\x6d\x10\xe4\x81\x04\xd8\xb4\x41\xb2\xbd\x2d\x70\x70\x50\x70\xa5\xb1\xc5\xae\x24\x2a\x24\xb5\x2f\x57\xdf\xfd\x60\x48\xea\xe5\xf5\x7a\xb7\x69\x71\xfe\xe9\xc6\xe4\xcc\x6f\x1e\xfc\xcd\x0c\x69\x77\xbb\x4d\x70\x2d\x0a\x04\x96\x22\x4f\x58\x5d\xaf\x52\x93\x67\xc7\xa3\xd1\x8a\x3e\x1f\x8f\x82\x95\x11\x26\xc3\xe3\xed\x16\xc2\x4f\xdc\xa4\x50\xd7\xab\xb9\x5b\x1a\x05\xdb\xad\x58\x43\xf8\x09\x8b\x44\x14\x9b\xba\x5e\xe5\x68\x38\xa4\xc6\x94\x33\xfc\x5a\x89\x8b\x88\x29\x5c\x2b\xd4\x29\x83\x58\x16\x06\x0b\x13\xb1\x67\xec\x78\xbb\xc5\x22\xa9\xeb\x51\xb0\xd2\xe6\xda\xe2\x04\x64\x73\x3a\x0a\x82\x33\x99\x5c\xc3\x76\x14\x04\x41\x8a\x62\x93\x9a\x25\x1c\x2d\x16\xdf\xbd\xa0\x85\x4b\x91\x98\xb4\xf7\x79\x2d\x0b\x33\x5b\xf3\x5c\x64\xd7\x4b\x78\x2d\x2b\x25\x50\x4d\x21\x97\x85\xd4\x25\x8f\x91\x64\xea\xd1\x28\x08\xb8\xc3\x33\x78\x65\x66\x09\xc6\x52\x71\x23\x64\xb1\x84\x42\x16\x9d\x90\xe1\x67\x19\x3a\xc1\x9c\xab\x8d\x28\x96\xf0\xfd\x77\xed\x6e\x58\x52\xe0\xdb\xd6\xaa\x75\x7b\x09\x55\x91\xa0\xca\x44\x0f\x26\x2c\x78\xee\x61\xac\x3d\x9e\x89\x4d\xb1\x04\x45\xa1\x90\x50\x50\xf2\x84\x52\x35\xb3\x2b\x4b\x78\xb6\x28\xaf\x76\x94\xbd\xbb\x97\x52\x25\xb3\x4b\xc5\xcb\x25\x9c\x29\xe4\xe7\x33\x5a\x20\xd1\x20\x11\xba\xcc\xf8\xf5\x12\x44\x41\xb6\x67\x67\x99\x8c\xcf\xfb\x19\x7a\xb6\x18\xa0\x6a\x71\xb3\xc7\xa5\x0c\xd7\xa6\x93\xc9\x8d\xc8\x0f\x08\xb5\x6e\xd3\x42\xe7\x75\x70\x99\x0a\x83\x33\x9b\x6e\xca\x27\xb9\xdb\x61\xae\x45\x66\x50\xed\xe6\x14\xbe\xff\x0e\x16\x3b\xb9\xdd\xa0\xee\x27\x57\xdc\xe0\x12\x16\xe1\x8f\x98\x77\x42\x5c\xc5\xa9\xb8\xc0\xfb\xc4\x5c\x06\xc3\x0b\x54\x5a\xc8\xc2\xa3\xfa\xb4\xf0\xca\xc8\x17\x87\xd5\x79\x4c\xd4\xd0\x70\x56\x19\x23\x0b\xd8\xde\xa2\x99\x28\x52\x54\xc2\x1c\x80\x09\x56\x73\x4f\xea\xd5\xdc\x15\xd0\x68\x45\xa4\xa6\x42\x5a\x4b\x95\x43\x9c\x71\xad\x23\xe6\xb2\xc3\x88\xfb\x2b\x51\x94\x95\x01\xf2\x3d\x62\x5f\x19\x5c\xf0\xac\xc2\x88\x51\xc1\xbd\xb3\x52\x50\xd7\x0c\xca\x8c\xc7\x98\xca\x2c\x41\xd5\x68\xc3\x98\x0e\x0b\xa4\x82\x4d\x26\xcf\x26\x7d\x30\x73\x5d\x62\xc4\x52\x91\x24\x58\x30\x0f\xad\xa5\x32\x03\xf4\x2f\x52\x19\xc2\xbe\x47\x4f\xaa\x04\xd5\x40\xf1\x67\x5a\x69\x34\x5d\x0f\x38\x11\xb9\x30\x75\x7d\x00\x26\x23\x89\x01\x8c\xd5\xb1\x30\x5d\x47\x98\x53\x92\x28\x59\xb6\x22\x09\x7f\x65\x14\xfd\x09\x56\x26\x6d\x92\x47\xf1\xd8\x68\x83\x60\xc5\x21\x55\xb8\xee\x02\x3a\x11\xc5\x39\x30\xbc\x32\xcc\x22\x9f\x5e\x97\x48\x5b\xaf\x94\x92\x97\xed\xfa\x6a\xce\x0f\xaa\x5b\x03\x56\xff\x27\x9e\xf7\xf5\x9b\x8d\x06\x60\x35\x37\xe9\xae\x77\x44\x2d\x76\x7c\x07\xb2\xdd\xb4\xc8\x5f\xc4\x4d\x1f\xb9\xd9\x20\xe4\x7d\xa8\xb6\x44\xef\x84\x75\xbb\x16\xf7\xa3\x4c\xc4\x5a\x60\xd2\xc3\x6e\x77\x07\xe0\xee\xe0\x7e\x55\xc2\x60\x5d\xf7\x2c\xf9\x2a\x68\x12\xec\x8b\x21\xe1\x86\xcf\x64\x19\xb1\xfc\x3c\x11\x8a\xb9\xcf\xd4\x17\x23\x36\xef\xcd\x06\x76\x5c\xe0\x25\xac\x2d\x4f\x57\x73\xa7\xeb\x80\x9c\xb9\x5f\xca\x4c\xf2\x64\x87\x28\x6b\x91\x21\x83\xbc\xca\x8c\x28\x33\xec\x4c\x55\x56\xf8\x80\xad\x96\x37\xfe\x24\xba\xcf\xab\xb9\xa3\xcd\xca\xa8\x26\x2e\xb2\x02\xc2\x60\xce\x7c\x6e\x93\x43\x7c\x1a\x1a\x0a\x7b\x07\x9e\xec\xaa\xdb\xa3\x3b\x9e\xed\xdb\x6a\x4e\xcd\x6f\x35\x5e\xd9\x5c\x14\x48\x91\x28\x2c\x0c\x30\x56\xd7\x7f\xc7\x59\xe7\xab\x85\xb2\xde\xfe\x93\xee\x36\x39\xed\xfe\x36\x17\x06\x25\x2f\xbf\xcd\x6f\x1b\x7e\xf8\x2a\x8e\x51\x6b\x71\x96\x61\x5d\xef\x0b\xc7\x9e\xb1\x13\xfd\xa0\xdf\x08\x55\xd7\x73\xef\x03\x5d\x21\x20\xa4\xd2\xf4\x9c\x86\xed\x16\x33\x8d\x75\x0d\xbd\x0d\x68\x3c\xee\x4c\xfe\xbf\x1f\x0c\x75\xbd\xe2\x8d\x5f\xcd\xb0\x60\x3d\xe3\x8d\x9c\x25\x59\x23\x40\x39\xed\x41\xee\xcb\x9f\x65\x02\xf0\xcc\xf8\xfa\xa4\xd1\x5b\xd7\x70\x76\x6d\x50\xf7\x63\x2f\xa4\x19\xc6\x3f\x73\x01\x40\xb3\xe7\x03\xde\x6e\xc1\x48\x02\x6d\xb1\x76\xe4\xde\x08\x45\x1b\x7a\x80\xd0\x5d\xc8\x1e\xa7\x98\x65\xa2\x7c\xd1\xa4\xe7\x36\x1e\x8c\xc9\xd1\x9f\xaa\xfc\x9d\xc8\x50\x53\xd6\x88\x79\x2d\x3f\xdb\x8d\xa3\xba\xd6\x3e\xf8\xc9\x3d\x49\xf0\x24\xba\x7d\xc8\x64\xe9\x23\x6d\x86\xef\xa4\xca\xb9\x01\xf6\x74\xb1\xf8\x61\xb6\x38\x9a\x2d\x9e\xc2\xd1\xf7\xcb\xc5\x73\x6a\x80\x1e\xbd\x45\xde\x69\x53\xc9\xfe\x36\xe5\xa4\x3e\xa3\x36\x52\x91\xdc\x6e\xd7\x52\x6e\x67\xd0\x4b\x06\x15\xee\x05\xda\x9e\xd5\x0b\x72\x4f\x0f\x94\x17\x07\xa1\xa8\x46\x87\xdd\xef\x16\x44\x82\x19\x9a\x03\x20\x6e\x7f\x00\x62\x53\xd2\xf9\xe5\x9a\xc9\xed\xb2\x5c\x4b\x69\x5c\x5d\xda\xa4\x48\x05\xe1\x27\x85\x17\x10\xfe\x84\x57\x66\xb7\x5e\x4b\xbe\x69\xa8\xd9\x1b\x01\xb7\x6a\x95\x00\xea\x7a\x58\xa0\x84\x49\xf5\xf1\x38\xe3\x5f\x2b\xf9\x02\x4a\x85\x17\xb7\x6b\xa4\x1d\x64\x7d\x38\xe7\xc9\x00\x8e\x96\xfc\xfc\xb8\x32\xf0\x58\x59\xcc\x3b\xe0\xfa\x7d\x09\x3c\xa2\x27\xea\xbe\x7e\x74\x4f\x7c\x3d\xdd\xfb\xc9\xbf\x1b\xd5\x1d\x95\x7f\x2a\x0d\xcf\xf6\x97\x7f\x5b\x81\x7d\x99\xfb\xa3\x7b\x23\xd4\xb7\x06\xe7\x54\x21\x11\xaa\x1f\x1a\xad\x1e\x8a\xec\xf8\x1e\x9f\x5e\xb9\x2b\xf9\xae\x4f\xfe\xa6\x7e\xc8\xab\x44\x5e\x16\x34\xd8\x81\x67\x19\x70\xbd\xd7\xf0\xfe\x41\x1c\xde\x88\x92\x1d\xdf\x88\x92\x88\x31\x3d\x20\x67\xb8\x62\xc7\x86\xab\x07\xc8\x85\x9b\x1b\x2b\x1a\x6e\x6e\x7a\x53\xf3\x56\xd4\x23\xfa\xe4\xef\xa4\x83\x96\x44\xcf\xd8\x58\x89\xd2\x90\xc6\x7c\x8e\x45\x0c\xa8\x63\x5e\xa2\x06\xe4\x71\x0a\x1a\x37\x39\x0d\x66\xb9\x06\x0e\x54\xe8\xb0\x96\x0a\x2a\x6a\xe2\x05\x70\xa8\x54\x36\x0a\x82\x0b\xae\x80\x34\x23\x58\x57\x85\x6d\x6c\x30\x2e\x27\xee\x05\xa2\xd0\x54\xaa\x80\x32\xd4\x65\x26\xcc\x98\xcd\xd9\x24\xcc\x79\x39\xc6\x22\x96\x09\xfe\xf2\xf9\xc3\x6b\x99\x97\xb2\xc0\xc2\x4c\xc2\x3f\xa4\x28\xac\x84\x7d\x8d\xd0\x7f\x12\x19\x57\x64\x3f\xe4\x49\xf2\xf6\x02\x0b\x73\x22\xb4\xc1\x02\xd5\x98\xc5\x99\x88\xcf\xd9\xb4\x67\x12\xbd\x49\x72\x47\x96\x10\x01\x86\x86\xab\x0d\x9a\x70\x83\xe6\x95\x31\x4a\x9c\x55\x06\xc7\xcc\x77\x31\x36\x21\x0b\x81\x58\xc3\xf8\x91\x2c\xe1\xcf\x3f\xad\x52\x14\x41\x73\x73\xf3\x70\x3e\x04\x2b\x5c\x37\xf8\x36\x15\x87\x2d\x90\x88\xb7\x41\x1e\xe5\x68\x52\x99\x40\x04\xec\xd3\xcf\x5f\x4e\x59\xbb\xfe\xb5\x42\x75\x4d\xcb\x2f\xdd\xd5\x35\x62\xf0\x04\x64\xd9\xfa\xd6\x38\xe5\x6e\xae\x8d\x4f\x84\x48\xa5\x02\x11\x94\x4a\xe6\xa5\x19\xb3\x77\xf6\xda\x6a\xdf\x2b\xde\xac\xd5\x7f\x44\x0b\x8d\x5a\x3f\x16\x17\x4c\xe0\xec\x3f\x89\x80\x3d\x26\x49\x6b\xfe\xf6\xe1\x8c\x69\xcf\xa1\xd6\xd0\x8c\xea\xce\x37\x9a\x28\x7d\xd7\x8c\xec\x39\xf6\x51\x5e\x20\x18\xc9\xa6\x96\x40\x7d\xd7\x8c\xa4\xb4\x93\x70\x14\xb9\xcd\x07\xf9\x69\xe4\x5d\x5e\x1a\x79\xa7\x8f\x7e\x64\x35\x16\x6c\x6a\x62\x59\xac\x85\xca\xc7\xec\x8d\xdd\x04\x42\x25\x37\xe0\x09\xb0\x97\x6c\x72\xc0\x9b\xee\x34\xdf\xbc\x3d\x79\x7b\xfa\x96\xbd\xe8\x39\x19\x01\x63\x43\xba\x5c\xa5\x0a\x22\xa0\xc7\xc5\x6f\x1f\x4f\xde\x1b\x53\x7e\xc6\xaf\x15\x6a\x33\x76\xee\x5e\xa5\x2a\x94\x25\x16\x63\x07\x3b\xa5\xd0\xc6\xe4\xc8\x04\x9e\x80\x0d\xbc\x93\xd3\x68\xbc\xf2\x7b\xe4\x09\x55\xc2\x6f\x33\xbf\x80\xc9\xec\x57\x61\x52\x36\x05\x36\x34\xe3\xf9\x40\xea\xd2\x35\xaf\x7e\xa9\xf6\x53\x42\x22\xda\x70\x53\x69\x38\x8e\xe0\xf9\x62\xd1\xe6\x80\x67\xa8\x8c\xdd\x57\xa8\x4b\x59\x68\x3c\xc5\x2b\xe3\x4f\xd3\x06\x1a\x64\x32\xb6\x5f\x4f\x85\x0a\xc9\x88\x0f\xae\xee\xb9\x5e\xf8\xc5\xda\xfe\x77\xf8\xa8\xb2\x0d\xc8\x0e\x05\xc8\xa8\x6c\x15\x98\x94\x17\x20\x0b\x84\x38\xad\x8a\x73\xdb\x75\x14\xea\x2a\xa7\x46\x06\xe4\xa2\x2b\x54\xed\x5b\x90\x93\x8a\xe0\x47\xf8\x3f\x38\x5a\x3c\x7d\xee\xff\xbc\xf0\xdb\x67\x3f\x3c\x1f\x84\xad\x87\x1d\xea\xcc\x48\x3e\xae\x0a\xd7\xfd\xf6\xb4\xa7\xb1\x9e\x4c\xba\xc6\x44\x80\x55\x79\x2b\x95\x89\x50\x53\x3b\x86\x87\xd8\x74\xf0\x9f\x94\xcc\x85\xc6\x71\x27\xac\x50\xcb\xec\x02\xa7\xa0\xf0\x0f\x8c\x8d\x57\x79\x08\x5f\x6c\xf5\x90\x99\x90\x26\x37\xac\x22\x97\xa1\x06\xa1\xe3\x13\xfb\xf4\xcb\x29\x73\x74\x4a\x84\x22\x36\xb1\xf9\x1d\xa5\x63\xe1\x6c\x95\x7b\x1b\x7f\x97\x6d\xf7\xf2\xcd\x93\xc2\xb1\x6d\x45\x64\x83\x97\xe0\x93\x32\x9e\xc0\xd2\xe7\xe5\x2e\xce\x05\xf5\xc0\x0c\x2a\x25\xa9\xc8\x9c\x52\x6f\x4b\x63\x91\xd8\xe8\x26\x2f\xee\xaa\x66\x4a\xf9\x39\xda\x2e\x6c\x2a\xbd\xa4\x0c\xfd\xab\x3d\x4a\xfb\xb5\xa8\xff\x27\xa5\xdb\xff\x33\xe3\xda\x34\xdf\x56\xfc\xdb\x8f\xae\x65\x13\x3a\x01\x92\xdd\x41\xdc\x95\xca\xa6\x20\xd7\x6b\x8d\xed\x59\xbb\xc3\x3e\x78\xd4\xc1\x55\x73\x94\xaf\x4e\x5f\xbf\x67\x53\x9a\xb9\xdd\xd6\xed\xf3\x39\xad\xf4\xec\x73\x53\x25\xd4\x0a\x8e\xc2\x45\xb8\x60\x87\x54\x5c\x05\xce\x7e\xb6\xae\xb1\xd6\xc7\x03\x1a\xaf\xdd\x17\xe2\x33\xfa\x82\x8a\x6c\xf0\xb2\xcc\x84\x2b\xfe\xb9\xd3\x7e\x22\x63\x83\xf4\x65\xb3\x42\x9e\xf7\xac\x1f\xe4\x03\xd1\xfa\xea\x8e\xfe\xe3\xda\x4b\xf6\xc5\x48\xc5\x37\x18\x2a\xcc\xe5\x05\x7e\x30\x98\x8f\xcf\xf1\xba\xc1\x6f\x2b\xae\xa1\xce\x5e\xe2\xf8\x53\x0f\x02\xe7\x2a\x8d\x2a\xae\x34\x7e\x28\xcc\xf8\x8a\x26\xf9\x67\xaf\xb3\x3f\x3f\x93\x29\x1c\x2d\x5a\x28\x72\xd9\xc3\x1c\x47\x1d\x49\xbe\xd9\x6d\xcf\xfe\x5d\x4f\x89\x4c\x03\x02\xed\xd4\xc0\x7c\x5e\xa0\xb9\x94\xea\x1c\xd6\x5c\x64\x95\xb2\x5d\x45\x57\x39\xc2\x5a\xc9\x1c\x4c\x8a\xa0\x51\x5d\xa0\xfa\x5f\xed\x11\xba\x03\x69\x2a\x67\xdf\x89\x68\x34\xa7\x22\x47\x59\x99\xf1\xbe\xed\xc0\xf6\x63\x1c\xf7\x38\x19\xd4\x53\x78\xb6\x58\x2c\x76\x3d\xbc\xea\x0a\x31\xd4\x99\x88\xd1\xa7\xad\x89\x08\x9e\xf8\x26\xe6\x15\xbd\x1e\x15\x88\x8f\xa4\xef\x21\x19\xfc\x86\x1a\x7a\xff\xf6\xd5\x9b\x7f\xa0\x84\x1e\x4e\xe2\x47\x51\x04\x4f\xbf\x99\xc5\xb1\x42\x6e\x0e\xb2\xe1\xaf\x13\xf7\xf6\xb9\xfc\x97\x19\xe0\x97\xfd\x2a\x1d\x9e\x0b\x73\xbf\xfd\xde\x28\xa3\x6b\xf3\x14\xd8\xfc\xf7\xdf\x2d\x95\xe7\x7e\xfa\xfb\xf7\x42\xdb\xed\xff\xea\x69\xee\x55\xf2\x99\x3b\xc1\x62\x63\x2f\x54\x5d\x61\x3f\x44\xed\x23\x1a\x4e\xaf\x0c\x6a\x8d\xa4\x49\x13\xc4\x5e\x2e\xcf\x7e\x78\xde\x1b\xb4\x34\x8e\xa7\x89\x50\xed\x16\xcd\xe8\xbe\x81\xfb\x79\xd6\x0d\x50\xc7\xb4\xa3\x6e\xbf\xeb\x29\x07\xa7\x68\x4b\x2a\x3a\x89\x4a\x65\x10\xd1\xed\x63\x1f\x9b\x4e\xfc\xf5\xae\x4d\xdd\x90\xcb\x1a\x4d\x43\xe4\x41\x85\xf5\xd8\xba\xf8\x96\xd1\x7d\x9b\x2f\xce\xcb\x81\xf1\x4d\x67\xdc\xcb\x93\xd0\x4b\xdf\x3a\x2c\x3b\x61\x39\xac\xa7\xfa\x61\xcf\xcc\x94\x17\x1b\xdc\xfb\xce\xa4\xd6\x7f\xdf\x13\x13\x1e\x3d\xf0\x41\x49\x34\xf8\x4b\xef\x49\xa2\x91\x86\x08\x5e\x29\xc5\xaf\xc3\x52\x49\x23\xe9\x77\x33\xd7\x5b\xc3\x98\x67\x59\xe7\x9c\x95\xb5\xe1\x06\xed\x5a\x22\x34\xdd\x10\xe8\x29\x60\x54\x65\x7f\x05\x0e\xac\x5c\xa8\x30\xa9\xe2\xfe\x45\xb5\x1c\xdc\x69\x1b\x5e\x95\xa1\x49\xb1\xd8\xd7\x14\xbc\x80\xab\xcf\xde\x9d\xd8\x1f\xa4\x4f\xff\xb4\xb9\x12\x87\xed\xd4\x9b\x84\x31\x37\x71\xda\xc3\x44\xa5\x1a\x58\xf7\x00\x41\xa5\xe8\xcd\xe8\x2b\xcd\x4e\x3b\x4c\x7c\x5a\xea\xc9\x5d\x2e\xdd\xf1\x32\x19\xbc\x45\xda\x2f\x4a\xda\x2f\x44\x9a\xb5\xd5\xdc\xfd\x08\x3a\x5a\xcd\xe9\xa7\xfe\xfe\xd7\x96\x06\xf3\x32\xe3\xa6\xf9\x1f\x10\x20\xa4\xaf\x80\x15\x51\xc6\xfe\xfe\x89\xba\xae\xfb\x42\xf4\xa3\x03\xfc\x4f\xf8\x59\x5e\x3a\x49\x0b\xd3\x17\x58\x4b\x69\x18\x84\x75\x3d\xfa\xcf\x00\x43\x16\x27\x29\xdb\x20\x00\x00")
 
 func staticListHtmlBytes() ([]byte, error) {
 	return bindataRead(
@@ -83,7 +84,27 @@ func staticListHtml() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "static/list.html", size: 1838, mode: os.FileMode(420), modTime: time.Unix(1524656120, 0)}
+	info := bindataFileInfo{name: "static/list.html", size: 8411, mode: os.FileMode(420), modTime: time.Unix(1792401016, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _staticLivereloadJs = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x94\x56\x51\x8f\xdb\x36\x12\x7e\xb6\x7f\xc5\xac\x0e\xc8\xc9\x38\x85\xde\x24\x7b\x97\x9c\x0d\x3f\x04\xdb\x14\x48\x11\xa4\x45\xb6\x79\x2a\xfa\x40\x93\x23\x89\x5d\x8a\x54\x49\xca\x5e\xb7\xf1\x7f\x2f\x86\xa4\x6d\x79\xeb\x6c\x5b\xc0\x0f\xa6\x38\xf3\xcd\xcc\xc7\xf9\x86\x9c\xcf\x3d\xba\x0d\x82\x56\x1b\x74\xa8\x2d\x97\x20\xb4\x42\x13\x2a\x50\xe6\x17\x14\x01\x25\x28\x13\x2c\xb4\xa1\xd3\xd0\xf3\x06\x7d\x35\x9d\xcf\x1d\x0a\x54\x1b\xf4\x20\x5a\x6e\x1a\x04\xdc\xa0\x09\x1e\x6a\x67\x3b\x08\x2d\x82\xe7\x1d\x42\x44\x76\xd3\xb2\x1e\x8c\x08\xca\x1a\x28\x67\xf0\xfb\x74\xa2\x6a\x28\xaf\xb6\xca\x48\xbb\x65\xef\xc8\xef\xce\x0e\x4e\x20\x7c\xf9\x02\x57\xd2\x8a\xa1\x43\x13\x98\x18\x9c\xa3\x2d\xe1\x54\x1f\xa2\xdb\xc4\x61\x18\x9c\x59\x4e\x27\xfb\xe9\x64\xc3\x1d\x0c\x4e\xc3\x0a\x0c\x6e\xe1\xf3\xa7\x0f\x65\x91\x52\x28\x2a\xb8\x8c\xc1\xbc\x13\xb3\x65\xf2\xf4\x81\x07\x84\x15\x14\xc2\x1a\x83\x22\x28\xd3\x14\x79\x2b\x17\x92\x70\x47\xd9\x95\x83\xd3\xe4\x9e\xf6\x19\x97\x32\x6e\x7e\x50\x3e\xa0\x41\x57\x16\xb6\x47\x53\x54\xf0\xa8\xd6\x49\xa4\x2a\x45\x41\x09\xbc\x0e\xe8\x40\x5b\xaf\x4c\x93\x78\x8a\x14\x55\xa0\x02\x74\x7c\x07\x2d\xdf\x20\x38\xf4\x81\xbb\x80\x72\x3a\x89\x5c\xe5\x6c\x57\x2b\x28\xb4\xf5\xa1\x48\xc0\x13\x6d\x05\xa7\x48\x2c\x1d\x5b\x49\xd9\x11\x35\x93\x63\x75\x31\x25\xe2\xeb\xc9\xc4\xd1\x39\xeb\x2e\x64\xfe\x28\x74\x04\xcb\xa1\x8f\x11\x62\x3e\x39\xee\xd3\x51\x6a\xae\xf4\xe0\xf0\x2c\x0e\x26\x38\xdf\xda\xed\xb7\x69\xbb\x44\x26\x79\xe0\xb3\xbf\x4c\x3a\xb5\xdd\x25\x34\x3a\xdf\xdc\x94\x2b\xf8\xee\xee\xfb\x8f\xac\xe7\xce\x8f\x91\xcf\x02\x9a\x41\xc7\x73\x8d\x4c\x27\x3f\xc6\x13\x62\x2c\x5b\x78\x7f\x20\xdc\x6f\x79\x7f\x17\x76\x1a\x7d\x8b\x18\xfc\xc1\xba\xe7\xa1\x8d\x08\x7b\x40\xed\x11\xbe\x02\xa4\xba\x66\x0c\xf4\xbe\x23\x29\x7d\x0d\xe3\xc9\x03\x8e\xcc\xcc\xe7\x1d\x0f\xa2\x45\x0f\x0e\x7b\xeb\x82\x87\x6d\x8b\xa1\x45\x07\xa8\xff\xed\x81\x87\xe0\x40\x79\xe0\x49\x89\xd6\xa9\x46\x99\xa8\x18\x5b\x03\x45\x83\x92\x9b\x5d\xfa\xb7\x6d\xd1\x40\xe4\x61\x3a\x39\xd2\x99\xd1\x4b\xd4\x55\x04\xab\xa2\xed\x89\xe2\x0d\xd7\x03\xb5\x00\x6a\xd6\x60\x78\x1b\x82\x53\xeb\x21\x60\x49\xb6\x47\x3e\xaf\xa2\x55\xae\x3a\xc9\x17\x6a\xae\x3d\xe6\x4a\xa2\xe2\x86\x91\x88\xa3\x7d\x05\xc7\xca\x5b\x87\x75\x44\xcb\xce\x03\xcb\x95\x10\xa5\x47\xab\xfc\xed\xd9\x33\x28\xaf\x28\x4b\x9a\x24\x12\x85\x95\xf8\xf9\xd3\xfb\x5b\xdb\xf5\xd6\xa0\x09\xe5\xc0\x68\xd3\xf0\x0e\x67\xd1\xff\x40\xfa\x7e\x3a\x99\xcf\xd7\x83\x0f\xc0\xa5\x24\xca\x04\x17\x2d\x02\x7d\x21\x99\xfe\x3a\xa0\xdb\x41\xb0\xc4\xde\x88\x20\xda\x2e\x47\xf5\xfd\xed\x52\x06\xe6\x91\x3b\xd1\xfe\xc0\x1d\xef\x3c\xf3\x18\xca\xe2\x34\x7d\x8b\x0a\xbe\xe1\x01\x99\xb1\xdb\x72\x76\x5e\x3a\x21\x1c\xd2\x7d\xd4\x8b\xd4\x04\x9a\x0b\xf4\x69\xa8\x8c\x36\xb4\x32\xf7\x54\x45\xb0\xf1\x00\x2b\xb0\x0e\xb8\xd6\x84\x61\x6b\xb2\xee\xf2\xf9\x5b\x83\x20\x2d\x94\x64\x15\x67\xd1\x1a\x41\x75\xd4\x5a\x28\x61\xbd\x03\x6e\x6c\x6c\xaf\x13\xf8\x6c\xc4\xc6\x63\x6d\x9c\x37\x0b\x25\xe1\x61\x05\x6f\x9d\xe3\x3b\xd6\x3b\x1b\x6c\xd8\xf5\xc8\xbc\x56\x02\x99\xe0\x5a\x97\xc7\xa1\x1d\xe9\xbe\x43\x8d\x22\x58\xf7\x56\x6b\x22\xc7\xdc\xff\xe4\x50\xaf\x4e\xa1\x7f\x2e\x12\x37\x04\x9e\x3a\x55\xc2\x0a\xc8\xd0\xb3\x5a\xe9\x80\x6e\x74\xe9\xd0\xe7\xf3\x1e\x3c\x34\x37\xed\x54\x50\x10\xb1\x45\x6e\x70\x42\xdd\x1f\x1b\x38\x63\x33\x8d\xa6\x09\x6d\xec\x9a\xeb\x0c\xf5\x4f\xc2\x3e\x1d\xf7\x38\x82\x72\xe0\xfd\xf4\x88\xce\x6a\xeb\xde\x71\xd1\x5e\x86\x9d\xcf\xa9\x63\xe8\x10\xa3\x80\x4e\xf4\xc0\x1a\x6b\xeb\x10\x1c\x76\x76\x73\xb8\x6a\xac\x96\x15\xb5\x31\xdf\x58\x25\x81\x43\xad\xb9\x6f\x09\x86\x48\x14\x9a\xce\x3f\xd5\xc2\xe2\xe2\xa3\x95\x98\x46\xce\x24\xae\x19\xb1\x04\xab\x28\x8b\x98\xc4\xb9\xee\x53\x31\xb3\xb1\x83\x35\x31\xbd\x15\x1c\x96\xf1\xa6\x81\xd5\x68\x66\x1f\xf8\x89\x78\x31\xdb\x43\xcc\x7d\x04\x8a\xdf\xe3\xa5\x59\x46\x90\xd3\xf1\x44\xd1\x8e\x06\x39\xd8\x0d\x3a\xcd\x77\x49\x01\x76\x08\xfd\x10\xc0\xd6\x54\x27\x57\x1a\x25\x58\x93\xaf\x05\x72\x14\xb6\xeb\xb8\x91\x51\x0d\x29\x6c\xf6\x4b\x20\x49\x12\x19\x44\xf9\x38\x1c\xd3\xf3\x20\x47\xa1\x91\x35\x68\xbd\x1c\x2b\x60\x74\xa9\x24\xcf\xd3\x35\x9a\xbd\x72\xb5\x79\x75\x5e\xef\x9f\x80\x49\xe7\x71\x88\xe6\x34\xa8\xf7\x68\x2b\x83\x1c\x1f\x43\xd1\xee\xe4\x7d\x7a\xfc\x38\xe4\x01\xdf\x69\xa4\x55\x59\xf4\x0e\x0b\xe2\xed\x60\xca\x62\xb7\x30\xe1\xfd\x8f\xf8\x10\xe8\x2d\xd4\x5b\xaf\xa8\x92\x45\xad\x1e\x50\x2e\x7f\x7b\xae\x8c\xc4\x87\xc5\xcb\x17\x37\xaf\x6f\xde\xbc\xfa\xdf\xcd\xeb\xa5\x32\x1e\xc3\xe2\x7a\xd9\x71\xd7\x28\xb3\xb8\x5e\xf6\x5c\x4a\x65\x9a\xc5\x4b\xec\x96\x84\x5b\x6b\xbb\x5d\xf0\x21\xd8\x65\x01\xff\xa1\xf3\x2b\xd6\x5c\xdc\x37\xce\x0e\x46\x2e\x5c\xb3\xe6\xe5\xcb\xeb\x2a\xfd\xae\xd9\xff\xff\x3b\x5b\x0a\xab\xad\x5b\xfc\xab\x7e\xf3\x66\x59\x5b\x13\x16\x2f\x5e\xf5\x0f\xf3\x17\xec\x06\x3a\x6b\xac\xef\xb9\xc0\xe5\xb6\x55\x01\x9f\xc7\xff\x8b\xde\xe1\xf3\xad\xe3\x7d\x31\x2e\x24\xe0\x43\xb8\xb5\x26\xa0\xa1\x32\x12\x5b\x67\xfb\x2a\x68\x6a\xed\xe2\x56\x2b\x71\x4f\x0a\x90\xca\x77\xca\xfb\x33\x94\x0b\x4f\x0c\x32\x3f\x7b\x61\x64\xea\x2f\x3e\x1f\x92\x74\x8f\xec\xaf\xad\xdc\x31\xde\xf7\x68\xe4\x6d\xab\xb4\x3c\xb6\xc0\x78\x80\xa7\x37\x00\xa4\xc1\x9f\x1a\x50\xa5\x4f\x14\x82\x74\x4b\x23\xe9\xd1\x94\xcd\x0f\x87\xd3\x80\x7d\x62\x72\xc6\x37\xc7\x85\x19\xa2\xba\x26\xd7\x72\x1a\x72\xbe\x54\x5d\x53\x41\xe1\x9d\x38\xcc\xc2\x6c\x33\x51\x5d\xc3\xbc\x13\x07\xf1\xd3\xf2\x5c\xfb\xe4\x93\xa5\xbf\x3f\x09\x74\x3f\x2b\x67\xcb\xe9\x1f\x03\x00\x29\x95\x3d\xb9\x61\x0c\x00\x00")
+
+func staticLivereloadJsBytes() ([]byte, error) {
+	return bindataRead(
+		_staticLivereloadJs,
+		"static/livereload.js",
+	)
+}
+
+func staticLivereloadJs() (*asset, error) {
+	bytes, err := staticLivereloadJsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "static/livereload.js", size: 3169, mode: os.FileMode(420), modTime: time.Unix(1792399498, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
@@ -141,6 +162,7 @@ func AssetNames() []string {
 // _bindata is a table, holding each asset generator, mapped to its name.
 var _bindata = map[string]func() (*asset, error){
 	"static/list.html": staticListHtml,
+	"static/livereload.js": staticLivereloadJs,
 }
 
 // AssetDir returns the file names below a certain
@@ -185,6 +207,7 @@ type bintree struct {
 var _bintree = &bintree{nil, map[string]*bintree{
 	"static": &bintree{nil, map[string]*bintree{
 		"list.html": &bintree{staticListHtml, map[string]*bintree{}},
+		"livereload.js": &bintree{staticLivereloadJs, map[string]*bintree{}},
 	}},
 }}
 
@@ -0,0 +1,102 @@
+//serve livereload client, injected into html pages,
+//receives change events from the same server
+(function () {
+	if (!window.EventSource || !document.currentScript) {
+		return;
+	}
+	var url = new URL("events", document.currentScript.src);
+	var state = "connecting";
+	var events = new EventSource(url);
+	events.addEventListener("open", function () {
+		//reconnected after losing the server, it may have restarted
+		if (state === "lost") {
+			location.reload();
+		}
+		state = "open";
+	});
+	events.addEventListener("error", function () {
+		if (state === "open") {
+			state = "lost";
+		}
+	});
+	events.addEventListener("failure", function (e) {
+		showFailure(e.data);
+	});
+	events.addEventListener("change", function (e) {
+		var change = JSON.parse(e.data);
+		showFailure(null);
+		if (change.action === "css") {
+			swapStylesheets(change.path);
+		} else if (change.action === "img") {
+			swapImages(change.path);
+		} else {
+			location.reload();
+		}
+	});
+	//matches reports whether el's attr is a same origin url of path (any path when null)
+	function matches(el, attr, path) {
+		var value = el.getAttribute(attr);
+		if (!value) {
+			return false;
+		}
+		var u = new URL(value, location.href);
+		return u.origin === location.origin && (!path || decodeURIComponent(u.pathname) === path);
+	}
+	//bust adds a cache busting query to url
+	function bust(value) {
+		var u = new URL(value, location.href);
+		u.searchParams.set("livereload", Date.now());
+		return u.href;
+	}
+	//swapStylesheets replaces the stylesheets linking to path, or all
+	//of them when none do (path may be imported by another stylesheet)
+	function swapStylesheets(path) {
+		var links = Array.prototype.slice.call(document.querySelectorAll("link[rel=stylesheet]"));
+		var matched = links.filter(function (link) {
+			return matches(link, "href", path);
+		});
+		if (matched.length === 0) {
+			matched = links.filter(function (link) {
+				return matches(link, "href", null);
+			});
+		}
+		matched.forEach(function (link) {
+			//load the new stylesheet before removing the old, to avoid a flash
+			var clone = link.cloneNode();
+			clone.href = bust(link.getAttribute("href"));
+			clone.onload = clone.onerror = function () {
+				link.remove();
+			};
+			link.after(clone);
+		});
+	}
+	//showFailure overlays the output of a failed on change
+	//command, or removes the overlay when output is null
+	var overlay = null;
+	function showFailure(output) {
+		if (overlay) {
+			overlay.remove();
+			overlay = null;
+		}
+		if (output === null) {
+			return;
+		}
+		overlay = document.createElement("pre");
+		overlay.style.cssText = "position:fixed;z-index:2147483647;inset:0;margin:0;padding:2em;overflow:auto;" +
+			"background:rgba(20,20,20,0.95);color:#f88;font:13px/1.4 monospace;white-space:pre-wrap";
+		overlay.textContent = output;
+		overlay.title = "Click to dismiss";
+		overlay.addEventListener("click", function () {
+			showFailure(null);
+		});
+		document.body.appendChild(overlay);
+	}
+	//swapImages reloads the images showing path
+	function swapImages(path) {
+		document.querySelectorAll("img").forEach(function (img) {
+			if (matches(img, "src", path)) {
+				img.src = bust(img.getAttribute("src"));
+			}
+		});
+	}
+})();
@@ -4,6 +4,7 @@ import (
 	"os/user"
 	"path/filepath"
 	"strings"
+	"unicode/utf8"
 )
 
 //util functions
@@ -18,3 +19,51 @@ func ShortenPath(s string) string {
 	s = strings.TrimSuffix(s, string(filepath.Separator))
 	return s
 }
+
+//naturalCompare compares strings case insensitively, with runs
+//of digits compared by value, so "build-2" < "build-10"
+func naturalCompare(a, b string) int {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	zeros := 0
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			da, db := digits(a), digits(b)
+			na, nb := strings.TrimLeft(da, "0"), strings.TrimLeft(db, "0")
+			if len(na) != len(nb) {
+				return compareInt(int64(len(na)), int64(len(nb)))
+			}
+			if c := strings.Compare(na, nb); c != 0 {
+				return c
+			}
+			//equal values, fewer leading zeros first
+			if zeros == 0 {
+				zeros = compareInt(int64(len(da)), int64(len(db)))
+			}
+			a, b = a[len(da):], b[len(db):]
+			continue
+		}
+		ra, sa := utf8.DecodeRuneInString(a)
+		rb, sb := utf8.DecodeRuneInString(b)
+		if ra != rb {
+			return compareInt(int64(ra), int64(rb))
+		}
+		a, b = a[sa:], b[sb:]
+	}
+	if c := compareInt(int64(len(a)), int64(len(b))); c != 0 {
+		return c
+	}
+	return zeros
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+//digits returns the leading run of digits in s
+func digits(s string) string {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i]
+}
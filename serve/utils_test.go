@@ -1,7 +1,23 @@
 package serve
 
-import "testing"
+import (
+	"sort"
+	"testing"
+)
 
 func TestDummy(t *testing.T) {
 	t.Log("Hello, world!")
 }
+
+func TestNaturalCompare(t *testing.T) {
+	names := []string{"build-10", "Build-2", "build-1", "build-002", "build-2a", "build", "v1.10.0", "v1.9.3"}
+	sort.Slice(names, func(i, j int) bool {
+		return naturalCompare(names[i], names[j]) < 0
+	})
+	expected := []string{"build", "build-1", "Build-2", "build-002", "build-2a", "build-10", "v1.9.3", "v1.10.0"}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, names)
+		}
+	}
+}